@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func buildCmd(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("build", &includes, &isaFile)
+	output := set.String("o", "", "write the output to `file` instead of the input name with a .bin extension")
+	raw := set.Bool("raw", false, "write the bare memory image without the container header")
+	lines := set.Bool("g", false, "include call site source lines in backtraces")
+	set.Parse(args)
+	if set.NArg() != 1 {
+		usageError(set, "expected a single input")
+	}
+	input := set.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + ".bin"
+	}
+	res := build(input, includes, isaFile, *lines)
+	bytes := res.Bytes
+	if !*raw {
+		bytes = res.Image().Encode()
+	}
+	if os.WriteFile(*output, bytes, 0644) != nil {
+		fmt.Fprintf(os.Stderr, "invalid output '%s'\n", *output)
+		os.Exit(1)
+	}
+}
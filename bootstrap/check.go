@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bootstrap/compiler"
+	"bootstrap/diag"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func checkCmd(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("check", &includes, &isaFile)
+	explain := set.Bool("explain-stack", false, "print every source line of the inputs with the type stack before and after it")
+	set.Parse(args)
+	if set.NArg() == 0 {
+		usageError(set, "expected at least one input")
+	}
+	ext := loadIsa(isaFile)
+	failed := false
+	for _, input := range set.Args() {
+		var diags []diag.Diagnostic
+		if strings.HasSuffix(input, ".mvasm") {
+			dat, err := os.ReadFile(input)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "unable to read '%s'\n", input)
+				failed = true
+				continue
+			}
+			_, diags = compiler.Assemble(input, string(dat), ext)
+		} else {
+			opts := compiler.Options{Entry: input, SearchPaths: searchPaths(includes), Builtin: builtin, Insts: ext}
+			if !*explain {
+				diags = compiler.Check(context.Background(), opts)
+			} else {
+				a := compiler.Analyze(context.Background(), opts)
+				diags = a.Diags
+				if err := a.ExplainStack(os.Stdout); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed = true
+				}
+			}
+		}
+		printDiags(diags)
+		failed = failed || diag.HasErrors(diags)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
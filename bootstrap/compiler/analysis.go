@@ -0,0 +1,405 @@
+package compiler
+
+import (
+	"bootstrap/diag"
+	"bootstrap/lexer"
+	"bootstrap/parser"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+type Stack struct {
+	Fun    string
+	Span   lexer.Span
+	Before []string
+	After  []string
+}
+
+type Ref struct {
+	Kind SymbolKind
+	Name string
+	Sig  string
+	Text string
+	Span lexer.Span
+	Decl lexer.Span
+	Doc  string
+}
+
+type Analysis struct {
+	c      *Ctx
+	Diags  []diag.Diagnostic
+	Stacks []Stack
+}
+
+func (c *Ctx) typNames(typs []parser.Typ) []string {
+	names := []string{}
+	for _, typ := range typs {
+		names = append(names, typ.String(c.types))
+	}
+	return names
+}
+
+func (c *Ctx) traceTyps(stack []parser.Typ) []string {
+	if !c.tracing {
+		return nil
+	}
+	return c.typNames(stack)
+}
+
+func (c *Ctx) trace(f *Fun, span lexer.Span, before []string, after []parser.Typ) {
+	if c.tracing {
+		c.stacks = append(c.stacks, Stack{Fun: f.makeFunIdent(c), Span: span, Before: before, After: c.traceTyps(after)})
+	}
+}
+
+func Analyze(ctx context.Context, opts Options) *Analysis {
+	c := &Ctx{ctx: ctx, fs: opts.FS, tracing: true}
+	if c.front(opts) {
+		idents := []string{}
+		for ident, f := range c.funs {
+			if f.fun.Span.File == c.modules[0].ast.File {
+				idents = append(idents, ident)
+			}
+		}
+		sort.Strings(idents)
+		for _, ident := range idents {
+			func() {
+				defer c.catch()
+				c.funs[ident].getInfo(c)
+			}()
+		}
+		idents = idents[:0]
+		for ident, f := range c.funs {
+			if f.info != nil {
+				idents = append(idents, ident)
+			}
+		}
+		sort.Strings(idents)
+		for _, ident := range idents {
+			f := c.funs[ident]
+			f.typeCheck(c, f.info.simpleTypeCheck)
+		}
+	}
+	return &Analysis{c: c, Diags: c.diags, Stacks: c.stacks}
+}
+
+func contains(span lexer.Span, offset int) bool {
+	return span.Start.Offset <= offset && offset <= span.End.Offset
+}
+
+func exprAt(exprs []parser.Expr, offset int) parser.Expr {
+	for _, expr := range exprs {
+		if !contains(expr.GetSpan(), offset) {
+			continue
+		}
+		var inner parser.Expr
+		if ifel := expr.AsIf(); ifel != nil {
+			if inner = exprAt(ifel.Con, offset); inner == nil {
+				if inner = exprAt(ifel.Exprs, offset); inner == nil {
+					inner = exprAt(ifel.Else, offset)
+				}
+			}
+		} else if while := expr.AsWhile(); while != nil {
+			if inner = exprAt(while.Con, offset); inner == nil {
+				inner = exprAt(while.Exprs, offset)
+			}
+		}
+		if inner != nil {
+			return inner
+		}
+		return expr
+	}
+	return nil
+}
+
+func (c *Ctx) funAt(file string, offset int) *parser.Fun {
+	m := c.loaded[file]
+	if m == nil {
+		return nil
+	}
+	for _, fun := range m.ast.Funs {
+		if contains(fun.Span, offset) {
+			return fun
+		}
+	}
+	return nil
+}
+
+func (c *Ctx) doc(span lexer.Span) string {
+	m := c.loaded[span.File]
+	if m == nil {
+		return ""
+	}
+	lines := []string{}
+	line := span.Start.Line - 1
+	for i := len(m.ast.Comments) - 1; i >= 0; i-- {
+		comment := m.ast.Comments[i]
+		if comment.Span.Start.Offset >= span.Start.Offset {
+			continue
+		}
+		if comment.Span.Start.Line != line || comment.Span.Start.Col != 1 {
+			break
+		}
+		text := strings.TrimPrefix(strings.TrimPrefix(comment.Content, "//"), " ")
+		lines = append([]string{strings.TrimRight(text, " \t\r")}, lines...)
+		line--
+	}
+	for len(lines) != 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) != 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func baseName(ident string, file string) string {
+	return strings.TrimPrefix(ident, file+":")
+}
+
+func srcTyps(typs []parser.Typ) string {
+	names := []string{}
+	for _, typ := range typs {
+		name := typName(typ)
+		if typ.IsNever() {
+			name = "!"
+		} else if _, ok := typ.(*parser.Custom); ok {
+			name = name[strings.LastIndex(name, ":")+1:]
+		} else {
+			name = strings.ToLower(name)
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *Ctx) funRef(span lexer.Span, fun *parser.Fun) *Ref {
+	name := baseName(fun.Ident.Content, fun.Span.File)
+	return &Ref{
+		Kind: FUN,
+		Name: name,
+		Sig:  c.makeFunIdent(name, fun.Inputs, fun.Outputs),
+		Text: name + "(" + srcTyps(fun.Inputs) + ":" + srcTyps(fun.Outputs) + ")",
+		Span: span,
+		Decl: fun.Ident.Span,
+		Doc:  c.doc(fun.Span),
+	}
+}
+
+func (c *Ctx) letRef(span lexer.Span, let *parser.Let) *Ref {
+	name := baseName(let.Ident.Content, let.Span.File)
+	return &Ref{
+		Kind: LET,
+		Name: name,
+		Sig:  "let " + name + ": " + let.Typ.String(c.types),
+		Text: name,
+		Span: span,
+		Decl: let.Ident.Span,
+		Doc:  c.doc(let.Span),
+	}
+}
+
+func (c *Ctx) callRef(span lexer.Span, call *parser.Call) *Ref {
+	f := c.funs[c.makeFunIdent(call.Ident.Content, call.Inputs, call.Outputs)]
+	if f == nil {
+		return nil
+	}
+	return c.funRef(span, f.fun)
+}
+
+func (c *Ctx) identRef(fun *parser.Fun, ident *parser.Ident) *Ref {
+	if fun != nil {
+		for _, let := range fun.Block.Lets {
+			if let.Ident.Content == ident.Content {
+				return c.letRef(ident.Span, let)
+			}
+		}
+	}
+	if let := c.lets[ident.Content]; let != nil {
+		return c.letRef(ident.Span, let.let)
+	}
+	return nil
+}
+
+func (a *Analysis) Resolved() bool {
+	return a.c.funs != nil
+}
+
+func (a *Analysis) Lookup(file string, offset int) *Ref {
+	c := a.c
+	if c.funs == nil || c.loaded[file] == nil {
+		return nil
+	}
+	for _, let := range c.loaded[file].ast.Lets {
+		if contains(let.Ident.Span, offset) {
+			return c.letRef(let.Ident.Span, let)
+		}
+	}
+	fun := c.funAt(file, offset)
+	if fun == nil {
+		return nil
+	}
+	if contains(fun.Ident.Span, offset) {
+		return c.funRef(fun.Ident.Span, fun)
+	}
+	exprs := []parser.Expr{}
+	for _, let := range fun.Block.Lets {
+		if contains(let.Ident.Span, offset) {
+			return c.letRef(let.Ident.Span, let)
+		}
+		exprs = append(exprs, let.Exprs...)
+	}
+	expr := exprAt(append(exprs, fun.Block.Exprs...), offset)
+	if expr == nil {
+		return nil
+	}
+	if call := expr.AsCall(); call != nil {
+		return c.callRef(call.Span, call)
+	} else if ident := expr.AsIdent(); ident != nil {
+		return c.identRef(fun, ident)
+	} else if addr := expr.AsAddr(); addr != nil && addr.Call != nil {
+		return c.callRef(addr.Span, addr.Call)
+	} else if addr != nil {
+		return c.identRef(fun, addr.Ident)
+	}
+	return nil
+}
+
+func visible(file string, declFile string, ident string, opts []*parser.Ident) bool {
+	return declFile == file || (!isPriv(opts) && baseName(ident, declFile) == ident)
+}
+
+func (a *Analysis) Complete(file string, offset int, prefix string) []Ref {
+	c := a.c
+	refs := []Ref{}
+	for _, f := range c.funs {
+		fun := f.fun
+		name := baseName(fun.Ident.Content, fun.Span.File)
+		if strings.HasPrefix(name, prefix) && visible(file, fun.Span.File, fun.Ident.Content, fun.Opts) {
+			refs = append(refs, *c.funRef(fun.Ident.Span, fun))
+		}
+	}
+	for _, l := range c.lets {
+		let := l.let
+		name := baseName(let.Ident.Content, let.Span.File)
+		if strings.HasPrefix(name, prefix) && visible(file, let.Span.File, let.Ident.Content, let.Opts) {
+			refs = append(refs, *c.letRef(let.Ident.Span, let))
+		}
+	}
+	if fun := c.funAt(file, offset); fun != nil && c.funs != nil {
+		for _, let := range fun.Block.Lets {
+			if strings.HasPrefix(let.Ident.Content, prefix) {
+				refs = append(refs, *c.letRef(let.Ident.Span, let))
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Sig < refs[j].Sig
+	})
+	return refs
+}
+
+func (a *Analysis) StackAt(file string, offset int) *Stack {
+	c := a.c
+	fun := c.funAt(file, offset)
+	if fun == nil || c.funs == nil || !contains(fun.Block.Span, offset) {
+		return nil
+	}
+	ident := c.makeFunIdent(fun.Ident.Content, fun.Inputs, fun.Outputs)
+	var found *Stack
+	for i := range a.Stacks {
+		stack := &a.Stacks[i]
+		if stack.Fun != ident || stack.Span.File != file || stack.Span.End.Offset > offset {
+			continue
+		}
+		if found == nil || stack.Span.End.Offset >= found.Span.End.Offset {
+			found = stack
+		}
+	}
+	if found == nil {
+		inputs := c.traceTyps(fun.Inputs)
+		return &Stack{Fun: ident, Span: fun.Block.Span, Before: inputs, After: inputs}
+	}
+	return found
+}
+
+func FormatStack(typs []string) string {
+	return "[" + strings.Join(typs, ", ") + "]"
+}
+
+func (a *Analysis) lineStacks(fun string, file string, line int) ([]string, []string, bool) {
+	var first, last, prev *Stack
+	for i := range a.Stacks {
+		stack := &a.Stacks[i]
+		if stack.Fun != fun || stack.Span.File != file {
+			continue
+		}
+		if stack.Span.Start.Line == line && (first == nil || stack.Span.Start.Offset < first.Span.Start.Offset) {
+			first = stack
+		}
+		if stack.Span.End.Line == line && (last == nil || stack.Span.End.Offset >= last.Span.End.Offset) {
+			last = stack
+		}
+		if stack.Span.End.Line < line && (prev == nil || stack.Span.End.Offset >= prev.Span.End.Offset) {
+			prev = stack
+		}
+	}
+	if first == nil && last == nil {
+		return nil, nil, false
+	}
+	var before, after []string
+	if first != nil {
+		before = first.Before
+	} else if last.Span.Start.Line < line {
+		before = last.Before
+	} else if prev != nil {
+		before = prev.After
+	}
+	if last != nil {
+		after = last.After
+	} else {
+		after = before
+	}
+	return before, after, true
+}
+
+func (a *Analysis) ExplainStack(w io.Writer) error {
+	c := a.c
+	if !a.Resolved() {
+		return nil
+	}
+	m := c.modules[0]
+	dat, err := fs.ReadFile(c.fs, m.ast.File)
+	if err != nil {
+		return fmt.Errorf("unable to read '%s'", m.ast.File)
+	}
+	lines := strings.Split(strings.ReplaceAll(string(dat), "\t", "    "), "\n")
+	for i, fun := range m.ast.Funs {
+		ident := c.makeFunIdent(fun.Ident.Content, fun.Inputs, fun.Outputs)
+		start, end := fun.Span.Start.Line, fun.Span.End.Line
+		width := 0
+		for line := start; line <= end; line++ {
+			lines[line-1] = strings.TrimRight(lines[line-1], " \r")
+			if len(lines[line-1]) > width {
+				width = len(lines[line-1])
+			}
+		}
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", ident)
+		for line := start; line <= end; line++ {
+			before, after, ok := a.lineStacks(ident, m.ast.File, line)
+			if !ok {
+				fmt.Fprintf(w, "%5d | %s\n", line, lines[line-1])
+				continue
+			}
+			fmt.Fprintf(w, "%5d | %-*s    %s -> %s\n", line, width, lines[line-1], FormatStack(before), FormatStack(after))
+		}
+	}
+	return nil
+}
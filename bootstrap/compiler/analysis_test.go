@@ -0,0 +1,28 @@
+package compiler
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeOrder(t *testing.T) {
+	src := prelude
+	for _, name := range []string{"e", "b", "d", "a", "c"} {
+		src += "fun{safe} " + name + "(:) {\n    1u64\n}\n\n"
+	}
+	first := ""
+	for i := 0; i < 20; i++ {
+		a := Analyze(context.Background(), Options{Entry: "main.mvm", FS: files(map[string]string{"main.mvm": src}), Builtin: os.DirFS("..")})
+		got := messages(a.Diags)
+		if i == 0 {
+			first = got
+			if strings.Count(got, "does not have a valid stack") != 5 || strings.Index(got, "'a(:)'") > strings.Index(got, "'b(:)'") {
+				t.Fatalf("unexpected diagnostics:\n%s", got)
+			}
+		} else if got != first {
+			t.Fatalf("run %d reported\n%s\nwant\n%s", i, got, first)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAsmFuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		body  string
+		want  []uint8
+	}{
+		{
+			"immediate",
+			"fun{unsafe, inline, asm} big(:u64) {\n    \"push_imm_u64 0x1000\"\n}\n",
+			"big(:u64)",
+			le("0000000000001000"),
+		},
+		{
+			"signed immediate",
+			"fun{unsafe, inline, asm} all(:u64) {\n    \"push_imm_u64 -1\"\n}\n",
+			"all(:u64)",
+			le("ffffffffffffffff"),
+		},
+		{
+			"inline immediate",
+			"fun{unsafe, inline, asm} three(:u8) {\n    \"push_imm_u8 3\"\n}\n",
+			"three(:u8) three(:u8)",
+			le("0303"),
+		},
+		{
+			"forward label",
+			"fun{unsafe, inline, asm} pick(u8,bool:u8) {\n    \"branch_imm_f done\"\n    \"push_imm_u8 1\"\n    \"add_u8\"\n    \"done:\"\n}\n",
+			"5 true(:bool) pick(u8,bool:u8) 5 false(:bool) pick(u8,bool:u8)",
+			le("0506"),
+		},
+		{
+			"backward label",
+			"fun{unsafe, inline, asm} count(:u8) {\n    \"push_imm_u8 0\"\n    \"loop:\"\n    \"push_imm_u8 1\"\n    \"add_u8\"\n    \"dup_u8\"\n    \"push_imm_u8 5\"\n    \"less_u8\"\n    \"branch_imm_b loop\"\n}\n",
+			"count(:u8)",
+			le("05"),
+		},
+	}
+	for _, test := range tests {
+		if got := stackOf(t, test.decls, test.body); !bytes.Equal(got, test.want) {
+			t.Errorf("%s: stack % x, want % x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAsmErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		call string
+		want string
+	}{
+		{
+			"missing immediate",
+			"fun{unsafe, inline, asm} f(:u8) {\n    \"push_imm_u8\"\n}\n",
+			"f(:u8) drop(u8:)",
+			"main.mvm:4:5: error: the instruction 'push_imm_u8' needs an immediate",
+		},
+		{
+			"extra immediate",
+			"fun{unsafe, inline, asm} f(:) {\n    \"nop 1\"\n}\n",
+			"f(:)",
+			"main.mvm:4:5: error: the instruction 'nop' doesn't take an immediate",
+		},
+		{
+			"invalid immediate",
+			"fun{unsafe, inline, asm} f(:u8) {\n    \"push_imm_u8 256\"\n}\n",
+			"f(:u8) drop(u8:)",
+			"main.mvm:4:5: error: invalid immediate '256'",
+		},
+		{
+			"unknown label",
+			"fun{unsafe, inline, asm} f(:) {\n    \"jump_imm_f nowhere\"\n}\n",
+			"f(:)",
+			"main.mvm:4:5: error: unknown label 'nowhere'",
+		},
+		{
+			"duplicate label",
+			"fun{unsafe, inline, asm} f(:) {\n    \"a:\"\n    \"a:\"\n}\n",
+			"f(:)",
+			"main.mvm:5:5: error: the label 'a' already exists",
+		},
+		{
+			"absolute label",
+			"fun{unsafe, inline, asm} f(:) {\n    \"a:\"\n    \"jump_imm a\"\n}\n",
+			"f(:)",
+			"main.mvm:5:5: error: the label 'a' can only be used with relative jumps",
+		},
+		{
+			"unreachable label",
+			"fun{unsafe, inline, asm} f(:) {\n    \"jump_imm_b a\"\n    \"a:\"\n}\n",
+			"f(:)",
+			"main.mvm:4:5: error: the label 'a' can't be reached with 'jump_imm_b'",
+		},
+		{
+			"unknown instruction",
+			"fun{unsafe, inline, asm} f(:) {\n    \"nope\"\n}\n",
+			"f(:)",
+			"main.mvm:4:5: error: invalid asm instruction 'nope'",
+		},
+		{
+			"stack",
+			"fun{unsafe, inline, asm} f(:) {\n    \"push_imm_u8 1\"\n}\n",
+			"f(:)",
+			"main.mvm:3:31: error: the fun 'f(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote:           U8",
+		},
+	}
+	for _, test := range tests {
+		src := prelude + test.src + "\nfun{unsafe} main(:) {\n    " + test.call + "\n}\n"
+		_, diags := compileSrc(t, src)
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
@@ -0,0 +1,222 @@
+package compiler
+
+import (
+	"bootstrap/diag"
+	"bootstrap/isa"
+	"bootstrap/lexer"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type asmLine struct {
+	span    lexer.Span
+	label   string
+	op      string
+	operand string
+	addr    uint64
+}
+
+type assembler struct {
+	diags  []diag.Diagnostic
+	lines  []*asmLine
+	labels map[string]uint64
+	insts  *instSet
+}
+
+func (a *assembler) errorf(span lexer.Span, format string, args ...interface{}) {
+	a.diags = append(a.diags, diag.Errorf(span, format, args...))
+}
+
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func splitInst(text string) (string, string, string) {
+	text = strings.TrimSpace(text)
+	if strings.HasSuffix(text, ":") && !strings.ContainsAny(text, " \t") {
+		return strings.TrimSuffix(text, ":"), "", ""
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", "", ""
+	}
+	return "", fields[0], strings.TrimSpace(strings.TrimPrefix(text, fields[0]))
+}
+
+func (a *assembler) parse(file string, src string) {
+	offset := 0
+	for i, text := range strings.Split(src, "\n") {
+		code := stripComment(text)
+		trimmed := strings.TrimSpace(code)
+		col := strings.Index(code, trimmed) + 1
+		start := lexer.Pos{Offset: offset + col - 1, Line: i + 1, Col: col}
+		end := lexer.Pos{Offset: start.Offset + len(trimmed), Line: i + 1, Col: col + len(trimmed)}
+		offset += len(text) + 1
+		if trimmed == "" {
+			continue
+		}
+		line := &asmLine{span: lexer.Span{File: file, Start: start, End: end}}
+		line.label, line.op, line.operand = splitInst(trimmed)
+		a.lines = append(a.lines, line)
+	}
+}
+
+func isImm(text string) bool {
+	text = strings.TrimPrefix(text, "-")
+	return text != "" && strings.ContainsAny(text[:1], "0123456789")
+}
+
+func parseImm(text string, size int) ([]uint8, bool) {
+	num, ok := new(big.Int).SetString(text, 0)
+	buf := make([]uint8, size)
+	if ok && num.Sign() < 0 && new(big.Int).Neg(num).Cmp(new(big.Int).Lsh(big.NewInt(1), uint(size*8-1))) <= 0 {
+		num.Add(num, new(big.Int).Lsh(big.NewInt(1), uint(size*8)))
+	}
+	if !ok || num.Sign() < 0 || num.BitLen() > size*8 {
+		return buf, false
+	}
+	bytes := num.Bytes()
+	for i := range bytes {
+		buf[i] = bytes[len(bytes)-1-i]
+	}
+	return buf, true
+}
+
+func (a *assembler) number(span lexer.Span, text string, size int) []uint8 {
+	buf, ok := parseImm(text, size)
+	if !ok {
+		a.errorf(span, "invalid immediate '%s'", text)
+	}
+	return buf
+}
+
+func (a *assembler) size(line *asmLine) uint64 {
+	switch line.op {
+	case "":
+		return 0
+	case ".data":
+		return uint64(len(strings.Fields(line.operand)))
+	case ".string":
+		str, err := strconv.Unquote(line.operand)
+		if err != nil {
+			a.errorf(line.span, "invalid string %s", line.operand)
+		}
+		return uint64(len(str))
+	}
+	if imm, ok := a.insts.lookup(line.op); ok {
+		if imm.size == 0 && line.operand != "" {
+			a.errorf(line.span, "the instruction '%s' doesn't take an immediate", line.op)
+		} else if imm.size != 0 && line.operand == "" {
+			a.errorf(line.span, "the instruction '%s' needs an immediate", line.op)
+		}
+		return 1 + uint64(imm.size)
+	}
+	a.errorf(line.span, "invalid asm instruction '%s'", line.op)
+	return 0
+}
+
+func (a *assembler) emit(line *asmLine) []uint8 {
+	switch line.op {
+	case "":
+		return nil
+	case ".data":
+		bytes := []uint8{}
+		for _, field := range strings.Fields(line.operand) {
+			bytes = append(bytes, a.number(line.span, field, 1)...)
+		}
+		return bytes
+	case ".string":
+		str, _ := strconv.Unquote(line.operand)
+		return []uint8(str)
+	}
+	imm, _ := a.insts.lookup(line.op)
+	if imm.size == 0 {
+		return []uint8{imm.code}
+	}
+	bytes := []uint8{imm.code}
+	target, ok := a.labels[line.operand]
+	if !ok && !isImm(line.operand) {
+		a.errorf(line.span, "unknown label '%s'", line.operand)
+	} else if !ok {
+		return append(bytes, a.number(line.span, line.operand, imm.size)...)
+	}
+	return append(bytes, encodeLabel(a.errorf, line.span, line.op, line.operand, imm, target, line.addr, true)...)
+}
+
+func Assemble(file string, src string, ext []isa.Inst) (*Result, []diag.Diagnostic) {
+	a := &assembler{labels: make(map[string]uint64)}
+	insts, err := newInstSet(ext)
+	if err != nil {
+		a.errorf(lexer.Span{}, "%s", err)
+		return nil, a.diags
+	}
+	a.insts = insts
+	a.parse(file, src)
+
+	var addr uint64
+	for _, line := range a.lines {
+		line.addr = addr
+		if line.label != "" {
+			if _, ok := a.labels[line.label]; ok {
+				a.errorf(line.span, "the label '%s' already exists", line.label)
+			}
+			a.labels[line.label] = addr
+		}
+		addr += a.size(line)
+	}
+	if diag.HasErrors(a.diags) {
+		return nil, a.diags
+	}
+
+	res := &Result{Bytes: []uint8{}, Symbols: []Symbol{}}
+	for i, line := range a.lines {
+		res.Bytes = append(res.Bytes, a.emit(line)...)
+		if line.label == "" {
+			continue
+		}
+		kind := FUN
+		end := addr
+		for _, next := range a.lines[i+1:] {
+			if next.label != "" {
+				end = next.addr
+				break
+			}
+		}
+		for _, next := range a.lines[i+1:] {
+			if next.op != "" {
+				if next.op == ".data" || next.op == ".string" {
+					kind = LET
+				}
+				break
+			}
+		}
+		if line.label == ".strings" {
+			res.Strings = Strings{Addr: line.addr}
+			continue
+		}
+		res.Symbols = append(res.Symbols, Symbol{Kind: kind, Name: line.label, Addr: line.addr, Size: end - line.addr, Span: line.span})
+	}
+	if diag.HasErrors(a.diags) {
+		return nil, a.diags
+	}
+	if res.Strings.Addr != 0 {
+		res.Strings.Data = string(res.Bytes[res.Strings.Addr:])
+	}
+	return res, a.diags
+}
@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAssembleRoundTrip(t *testing.T) {
+	res := mustCompile(t, prelude+fib)
+	var out bytes.Buffer
+	if err := Disassemble(&out, res.Bytes, res.Symbols, res.Strings, nil); err != nil {
+		t.Fatal(err)
+	}
+	asm, diags := Assemble("fib.asm", out.String(), nil)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics:\n%s", messages(diags))
+	}
+	if !bytes.Equal(asm.Bytes, res.Bytes) {
+		t.Errorf("reassembled bytes differ")
+	}
+	if sym := symbol(asm, "fib(u64:u64)"); sym == nil || sym.Kind != FUN {
+		t.Errorf("fun symbol 'fib(u64:u64)' is %+v", sym)
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []uint8
+	}{
+		{"decimal", "push_imm_u8 7", []uint8{10, 7}},
+		{"hex", "push_imm_u8 0xff", []uint8{10, 0xff}},
+		{"minus one", "push_imm_u8 -1", []uint8{10, 0xff}},
+		{"min", "push_imm_u8 -128", []uint8{10, 0x80}},
+		{"negative hex", "push_imm_u8 -0x2", []uint8{10, 0xfe}},
+		{"data", ".data 1 -1 0x10", []uint8{1, 0xff, 0x10}},
+		{"string", ".string \"a;b\" ; comment", []uint8{'a', ';', 'b'}},
+		{"label", "start:\n    halt\n    jump_imm start", []uint8{1, 220, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"relative", "back:\n    halt\n    jump_imm_b back", []uint8{1, 222, 1, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		res, diags := Assemble("test.asm", test.src, nil)
+		if len(diags) != 0 {
+			t.Errorf("%s: unexpected diagnostics:\n%s", test.name, messages(diags))
+			continue
+		}
+		if !bytes.Equal(res.Bytes, test.want) {
+			t.Errorf("%s: bytes % x, want % x", test.name, res.Bytes, test.want)
+		}
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"too small", "push_imm_u8 -129", "test.asm:1:1: error: invalid immediate '-129'"},
+		{"too big", "push_imm_u8 256", "test.asm:1:1: error: invalid immediate '256'"},
+		{"unknown label", "jump_imm nowhere", "test.asm:1:1: error: unknown label 'nowhere'"},
+		{"negative label", "jump_imm -nowhere", "test.asm:1:1: error: unknown label '-nowhere'"},
+		{"duplicate label", "a:\na:", "test.asm:2:1: error: the label 'a' already exists"},
+		{"no immediate", "halt 1", "test.asm:1:1: error: the instruction 'halt' doesn't take an immediate"},
+		{"missing immediate", "push_imm_u8", "test.asm:1:1: error: the instruction 'push_imm_u8' needs an immediate"},
+		{"unknown instruction", "nope", "test.asm:1:1: error: invalid asm instruction 'nope'"},
+		{"unreachable", "jump_imm_b ahead\nahead:", "test.asm:1:1: error: the label 'ahead' can't be reached with 'jump_imm_b'"},
+	}
+	for _, test := range tests {
+		_, diags := Assemble("test.asm", test.src, nil)
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+const backtraceEntrySize = 32
+
+type backtraceEntry struct {
+	addr uint64
+	size uint64
+	name string
+}
+
+func (c *Ctx) backtraceEntries(bytes []uint8) ([]backtraceEntry, []backtraceEntry) {
+	funs := []backtraceEntry{}
+	for ident, f := range c.funs {
+		if f.info != nil && !f.info.inline {
+			funs = append(funs, backtraceEntry{addr: f.info.pos, size: f.info.size, name: ident})
+		}
+	}
+	sort.Slice(funs, func(i, j int) bool {
+		return funs[i].addr < funs[j].addr
+	})
+	calls := []backtraceEntry{}
+	if !c.backtraceLines {
+		return funs, calls
+	}
+	seen := make(map[uint64]bool)
+	for _, line := range c.lines {
+		if line.Size != 9 || bytes[line.Addr] != 229 || seen[line.Addr] {
+			continue
+		}
+		seen[line.Addr] = true
+		loc := fmt.Sprintf("%s:%d:%d", line.Span.File, line.Span.Start.Line, line.Span.Start.Col)
+		calls = append(calls, backtraceEntry{addr: line.Addr, size: line.Size, name: loc})
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].addr < calls[j].addr
+	})
+	return funs, calls
+}
+
+func (c *Ctx) backtrace(bytes []uint8, addr uint64) []uint8 {
+	le := binary.LittleEndian
+	funs, calls := c.backtraceEntries(bytes)
+	entries := append(funs, calls...)
+	table := make([]uint8, 16+backtraceEntrySize*len(entries))
+	le.PutUint64(table, uint64(len(funs)))
+	le.PutUint64(table[8:], uint64(len(calls)))
+	strs := ""
+	for i, entry := range entries {
+		buf := table[16+backtraceEntrySize*i:]
+		le.PutUint64(buf, entry.addr)
+		le.PutUint64(buf[8:], entry.size)
+		le.PutUint64(buf[16:], addr+uint64(len(table)+len(strs)))
+		le.PutUint64(buf[24:], uint64(len(entry.name)))
+		strs += entry.name
+	}
+	return append(table, strs...)
+}
@@ -1,85 +1,265 @@
 package compiler
 
 import (
+	"bootstrap/diag"
+	"bootstrap/isa"
 	"bootstrap/lexer"
 	"bootstrap/parser"
 	"bytes"
-	"fmt"
+	"context"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 )
 
-func containsPath(path string, imported []string) bool {
-	for _, imp := range imported {
-		if path == imp {
-			return true
-		}
+type bailout struct{}
+
+type Options struct {
+	Entry       string
+	SearchPaths []string
+	FS          fs.FS
+	Builtin     fs.FS
+	Insts       []isa.Inst
+
+	BacktraceLines bool
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+const BuiltinDir = "<builtin>"
+
+type builtinFS struct {
+	fs      fs.FS
+	builtin fs.FS
+}
+
+func (b builtinFS) sub(name string) (fs.FS, string) {
+	if rest := strings.TrimPrefix(name, BuiltinDir+"/"); rest != name {
+		return b.builtin, rest
+	}
+	return b.fs, name
+}
+
+func (b builtinFS) Open(name string) (fs.File, error) {
+	fsys, name := b.sub(name)
+	return fsys.Open(name)
+}
+
+func (b builtinFS) ReadFile(name string) ([]uint8, error) {
+	fsys, name := b.sub(name)
+	return fs.ReadFile(fsys, name)
+}
+
+func (b builtinFS) Stat(name string) (fs.FileInfo, error) {
+	fsys, name := b.sub(name)
+	return fs.Stat(fsys, name)
+}
+
+type SymbolKind string
+
+const (
+	FUN SymbolKind = "fun"
+	LET SymbolKind = "let"
+)
+
+type Symbol struct {
+	Kind SymbolKind
+	Name string
+	Addr uint64
+	Size uint64
+	Span lexer.Span
+}
+
+type Strings struct {
+	Addr uint64
+	Data string
+}
+
+type Result struct {
+	Bytes   []uint8
+	Symbols []Symbol
+	Strings Strings
+	Lines   []Line
+}
+
+func (c *Ctx) canceled() bool {
+	if err := c.ctx.Err(); err != nil {
+		c.errorf(lexer.Span{}, "compilation canceled: %s", err)
+		return true
 	}
 	return false
 }
 
-func getAll(ast parser.Ast, imported []string) ([]string, []*parser.Fun, []*parser.Let, []*parser.Type) {
-	funs := ast.Funs
-	lets := ast.Lets
-	types := ast.Types
-	for _, imp := range ast.Imports {
-		currPath, err := filepath.Abs(".")
-		if err != nil {
-			panic(fmt.Sprintf("invalid import path '%s'", imp.Path.Content))
+func (c *Ctx) parseFile(file string) (parser.Ast, error) {
+	dat, err := fs.ReadFile(c.fs, file)
+	if err != nil {
+		return parser.Ast{}, err
+	}
+	ast, diags := parser.Parse(lexer.New(file, string(dat)))
+	c.diags = append(c.diags, diags...)
+	return ast, nil
+}
+
+func (c *Ctx) resolve(dir string, imp string) (string, []string) {
+	dirs := append([]string{dir}, c.searchPaths...)
+	if path.IsAbs(imp) {
+		dirs = []string{"/"}
+	}
+	for _, dir := range dirs {
+		file := path.Join(dir, imp)
+		if c.loaded[file] != nil {
+			return file, nil
 		}
-		path, err := filepath.Abs(imp.Path.Content)
-		if err != nil {
-			panic(fmt.Sprintf("invalid import path '%s'", imp.Path.Content))
+		if _, err := fs.Stat(c.fs, file); err == nil {
+			return file, nil
 		}
-		base := filepath.Dir(path)
-		if os.Chdir(base) != nil {
-			fmt.Println(path, base)
-			panic(fmt.Sprintf("invalid import path '%s'", imp.Path.Content))
+	}
+	return "", dirs
+}
+
+func (c *Ctx) load(ast parser.Ast) *module {
+	m := &module{ast: ast, aliases: make(map[string]*module)}
+	c.loaded[ast.File] = m
+	c.modules = append(c.modules, m)
+	for _, imp := range ast.Imports {
+		if c.canceled() {
+			break
 		}
-		if !containsPath(path, imported) {
-			imported = append(imported, path)
-			dat, err := os.ReadFile(path)
-			if err != nil {
-				panic(fmt.Sprintf("invalid import path '%s'", imp.Path.Content))
+		file, tried := c.resolve(path.Dir(ast.File), imp.Path.Content)
+		if file == "" {
+			d := diag.Errorf(imp.Span, "unable to find import '%s'", imp.Path.Content)
+			for _, dir := range tried {
+				d = d.Note(lexer.Span{}, "looked in '%s'", dir)
 			}
-			newAst, perr := parser.Parse(lexer.New(string(dat)))
-			if perr {
-				panic(fmt.Sprintf("error parsing file '%s'", imp.Path.Content))
+			c.report(d)
+			continue
+		}
+		mod := c.loaded[file]
+		if mod == nil {
+			newAst, err := c.parseFile(file)
+			if err != nil {
+				c.errorf(imp.Span, "unable to read import '%s'", file)
+				continue
 			}
-			newImported, newFuns, newLets, newTypes := getAll(newAst, imported)
-			imported = newImported
-			funs = append(funs, newFuns...)
-			lets = append(lets, newLets...)
-			types = append(types, newTypes...)
+			mod = c.load(newAst)
+		}
+		if imp.Alias == nil {
+			m.imports = append(m.imports, mod)
+			continue
 		}
-		if os.Chdir(currPath) != nil {
-			panic(fmt.Sprintf("invalid import path '%s'", imp.Path.Content))
+		alias := imp.Alias.Content
+		if strings.Contains(alias, ".") {
+			c.errorf(imp.Alias.Span, "the import alias '%s' can't contain a '.'", alias)
+		} else if m.aliases[alias] != nil {
+			c.errorf(imp.Alias.Span, "the import alias '%s' already exists", alias)
 		}
+		m.aliases[alias] = mod
 	}
-	return imported, funs, lets, types
+	return m
 }
 
-func Compile(ast parser.Ast) []uint8 {
-	_, allFuns, allLets, allTypes := getAll(ast, []string{})
-	c := Ctx{}
+func (c *Ctx) front(opts Options) bool {
+	insts, err := newInstSet(opts.Insts)
+	if err != nil {
+		c.errorf(lexer.Span{}, "%s", err)
+		return false
+	}
+	c.insts = insts
+	entry := path.Clean(opts.Entry)
+	for _, dir := range opts.SearchPaths {
+		c.searchPaths = append(c.searchPaths, path.Clean(dir))
+	}
+	if c.fs == nil {
+		abs, err := filepath.Abs(opts.Entry)
+		if err != nil {
+			c.errorf(lexer.Span{}, "invalid entry path '%s'", opts.Entry)
+			return false
+		}
+		c.fs = osFS{}
+		entry = filepath.ToSlash(abs)
+		for i, dir := range opts.SearchPaths {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				c.errorf(lexer.Span{}, "invalid search path '%s'", dir)
+				return false
+			}
+			c.searchPaths[i] = filepath.ToSlash(abs)
+		}
+	}
+	if opts.Builtin != nil {
+		c.fs = builtinFS{fs: c.fs, builtin: opts.Builtin}
+		c.searchPaths = append(c.searchPaths, BuiltinDir)
+	}
+	ast, err := c.parseFile(entry)
+	if err != nil {
+		c.errorf(lexer.Span{}, "unable to read '%s'", opts.Entry)
+		return false
+	}
+	c.loaded = make(map[string]*module)
+	for _, m := range c.load(ast).reach(nil) {
+		m.flat = true
+	}
+	if diag.HasErrors(c.diags) || c.canceled() {
+		return false
+	}
+	c.scope()
+	if diag.HasErrors(c.diags) {
+		return false
+	}
+
+	allFuns := []*parser.Fun{}
+	allLets := []*parser.Let{}
+	allTypes := []*parser.Type{}
+	for _, m := range c.modules {
+		allFuns = append(allFuns, m.ast.Funs...)
+		allLets = append(allLets, m.ast.Lets...)
+		allTypes = append(allTypes, m.ast.Types...)
+	}
+
 	c.types = parser.NewTypes()
 	for i := 0; i < len(allTypes); i++ {
 		typ := allTypes[i]
 		ident := typ.Ident.Content
 		if c.types.Set(ident, typ) {
-			panic(fmt.Sprintf("the type '%s' already exists", ident))
+			c.report(diag.Errorf(typ.Ident.Span, "the type '%s' already exists", ident).
+				Note(c.types.Get(ident).Ident.Span, "previous definition of '%s'", ident))
+		}
+	}
+
+	for _, typ := range allTypes {
+		c.checkTyps(typ.Ident.Span, typ.Fields...)
+	}
+	for _, let := range allLets {
+		c.checkTyps(let.Span, let.Typ)
+	}
+	for _, fun := range allFuns {
+		c.checkTyps(fun.Ident.Span, fun.Inputs...)
+		c.checkTyps(fun.Ident.Span, fun.Outputs...)
+		for _, let := range fun.Block.Lets {
+			c.checkTyps(let.Span, let.Typ)
+			c.checkExprTyps(let.Exprs)
 		}
+		c.checkExprTyps(fun.Block.Exprs)
+	}
+	if diag.HasErrors(c.diags) {
+		return false
 	}
 
 	c.lets = make(map[string]*Let)
 	for i := 0; i < len(allLets); i++ {
 		let := allLets[i]
 		ident := let.Ident.Content
-		if c.lets[ident] != nil {
-			panic(fmt.Sprintf("the let '%s' already exists", ident))
+		if prev := c.lets[ident]; prev != nil {
+			c.report(diag.Errorf(let.Ident.Span, "the let '%s' already exists", ident).
+				Note(prev.let.Ident.Span, "previous definition of '%s'", ident))
+			continue
 		}
 		c.lets[ident] = &Let{let: let}
 	}
@@ -88,12 +268,131 @@ func Compile(ast parser.Ast) []uint8 {
 	for i := 0; i < len(allFuns); i++ {
 		fun := allFuns[i]
 		ident := c.makeFunIdent(fun.Ident.Content, fun.Inputs, fun.Outputs)
-		if c.funs[ident] != nil {
-			panic(fmt.Sprintf("the fun '%s' already exists", ident))
+		if prev := c.funs[ident]; prev != nil {
+			c.report(diag.Errorf(fun.Ident.Span, "the fun '%s' already exists", ident).
+				Note(prev.fun.Ident.Span, "previous definition of '%s'", ident))
+			continue
 		}
 		c.funs[ident] = &Fun{fun: fun}
 	}
-	return c.compile()
+	c.infer(allFuns, allLets)
+	if diag.HasErrors(c.diags) {
+		return false
+	}
+	return true
+}
+
+func Check(ctx context.Context, opts Options) []diag.Diagnostic {
+	c := Ctx{ctx: ctx, fs: opts.FS}
+	if c.front(opts) {
+		c.check()
+	}
+	return c.diags
+}
+
+func Compile(ctx context.Context, opts Options) (*Result, []diag.Diagnostic) {
+	c := Ctx{ctx: ctx, fs: opts.FS, backtraceLines: opts.BacktraceLines}
+	if !c.front(opts) {
+		return nil, c.diags
+	}
+	bytes := c.compile()
+	if diag.HasErrors(c.diags) {
+		return nil, c.diags
+	}
+	sort.SliceStable(c.lines, func(i, j int) bool {
+		if c.lines[i].Addr != c.lines[j].Addr {
+			return c.lines[i].Addr < c.lines[j].Addr
+		}
+		return c.lines[i].Size > c.lines[j].Size
+	})
+	return &Result{Bytes: bytes, Symbols: c.symbols(), Strings: Strings{Addr: c.size, Data: c.strs}, Lines: c.lines}, c.diags
+}
+
+func symTyps(typs []parser.Typ) string {
+	names := []string{}
+	for _, typ := range typs {
+		if _, ok := typ.(*parser.Custom); ok {
+			names = append(names, typName(typ))
+		} else {
+			names = append(names, srcTyps([]parser.Typ{typ}))
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func symName(fun *parser.Fun) string {
+	return fun.Ident.Content + "(" + symTyps(fun.Inputs) + ":" + symTyps(fun.Outputs) + ")"
+}
+
+func (c *Ctx) symbols() []Symbol {
+	syms := []Symbol{}
+	for _, f := range c.funs {
+		if f.info != nil && !f.info.inline {
+			syms = append(syms, Symbol{Kind: FUN, Name: symName(f.fun), Addr: f.info.pos, Size: f.info.size, Span: f.fun.Span})
+		}
+	}
+	for ident, l := range c.lets {
+		if l.info != nil {
+			syms = append(syms, Symbol{Kind: LET, Name: ident, Addr: l.info.pos, Size: l.info.size, Span: l.let.Span})
+		}
+	}
+	syms = append(syms, Symbol{Kind: FUN, Name: ".entry", Addr: 0, Size: uint64(len(c.frameSetup(0)))})
+	syms = append(syms, Symbol{Kind: LET, Name: ".fp", Addr: c.fp, Size: 8})
+	syms = append(syms, Symbol{Kind: LET, Name: ".scratch", Addr: c.scratch, Size: 16})
+	sort.Slice(syms, func(i, j int) bool {
+		return syms[i].Addr < syms[j].Addr
+	})
+	return syms
+}
+
+func (c *Ctx) checkTyps(span lexer.Span, typs ...parser.Typ) {
+	for _, typ := range typs {
+		if custom, ok := typ.(*parser.Custom); ok && !c.types.Has(custom.Ident) {
+			c.errorf(span, "unknown type '%s'", custom.Ident)
+		}
+	}
+}
+
+func (c *Ctx) checkExprTyps(exprs []parser.Expr) {
+	for _, expr := range exprs {
+		if call := expr.AsCall(); call != nil {
+			c.checkTyps(call.Span, call.Inputs...)
+			c.checkTyps(call.Span, call.Outputs...)
+		} else if ifel := expr.AsIf(); ifel != nil {
+			c.checkExprTyps(ifel.Con)
+			c.checkExprTyps(ifel.Exprs)
+			c.checkExprTyps(ifel.Else)
+		} else if while := expr.AsWhile(); while != nil {
+			c.checkExprTyps(while.Con)
+			c.checkExprTyps(while.Exprs)
+		} else if wrap := expr.AsWrap(); wrap != nil {
+			c.checkTyps(wrap.Span, wrap.Typ)
+		} else if addr := expr.AsAddr(); addr != nil && addr.Call != nil {
+			c.checkTyps(addr.Span, addr.Call.Inputs...)
+			c.checkTyps(addr.Span, addr.Call.Outputs...)
+		}
+	}
+}
+
+func (c *Ctx) errorf(span lexer.Span, format string, args ...interface{}) {
+	c.report(diag.Errorf(span, format, args...))
+}
+
+func (c *Ctx) fatalf(span lexer.Span, format string, args ...interface{}) {
+	c.errorf(span, format, args...)
+	panic(bailout{})
+}
+
+func (c *Ctx) report(d diag.Diagnostic) {
+	c.diags = append(c.diags, d)
+}
+
+func (c *Ctx) catch() {
+	if r := recover(); r != nil {
+		if _, ok := r.(bailout); !ok {
+			panic(r)
+		}
+	}
 }
 
 func (f *Fun) makeFunIdent(c *Ctx) string {
@@ -164,57 +463,79 @@ func (f *Fun) comInfo(c *Ctx) {
 			f.info.inline = true
 		case "stc":
 			f.info.simpleTypeCheck = true
+		case "priv":
 		default:
-			panic(fmt.Sprintf("unknown fun option '%s' for fun '%s'", opt.Content, f.makeFunIdent(c)))
+			c.errorf(opt.Span, "unknown fun option '%s' for fun '%s'", opt.Content, f.makeFunIdent(c))
 		}
 	}
 
+	span := f.fun.Ident.Span
 	if f.info.asm && !f.info.inline {
-		panic(fmt.Sprintf("the asm fun '%s' needs to also be inline", f.makeFunIdent(c)))
+		c.errorf(span, "the asm fun '%s' needs to also be inline", f.makeFunIdent(c))
 	}
 	if f.info.asm && !(f.info.unsafe || f.info.safe) {
-		panic(fmt.Sprintf("the asm fun '%s' needs to either be unsafe or allow unsafe", f.makeFunIdent(c)))
+		c.errorf(span, "the asm fun '%s' needs to either be unsafe or allow unsafe", f.makeFunIdent(c))
 	}
 	if f.info.simpleTypeCheck && !(f.info.unsafe || f.info.safe) {
-		panic(fmt.Sprintf("the simple type check fun '%s' needs to either be unsafe or allow unsafe", f.makeFunIdent(c)))
+		c.errorf(span, "the simple type check fun '%s' needs to either be unsafe or allow unsafe", f.makeFunIdent(c))
 	}
 
 	if len(f.fun.Block.Lets) != 0 {
-		if f.info.inline {
-			panic(fmt.Sprintf("the inline fun '%s' can't have lets", f.makeFunIdent(c)))
-		}
 		if f.info.asm {
-			panic(fmt.Sprintf("the asm fun '%s' can't have lets", f.makeFunIdent(c)))
+			c.errorf(span, "the asm fun '%s' can't have lets", f.makeFunIdent(c))
 		}
 
 		f.info.lets = make(map[string]*Let)
 		for _, let := range f.fun.Block.Lets {
 			ident := let.Ident.Content
-			if f.info.lets[ident] != nil {
-				panic(fmt.Sprintf("the let '%s' already exists", ident))
+			if prev := f.info.lets[ident]; prev != nil {
+				c.report(diag.Errorf(let.Ident.Span, "the let '%s' already exists", ident).
+					Note(prev.let.Ident.Span, "previous definition of '%s'", ident))
+				continue
 			}
 			f.info.lets[ident] = &Let{let: let}
 			let := f.info.lets[ident]
 			let.getInfo(c).pos = f.info.letSize
+			let.info.loadSize = uint64(len(c.loadFrame(let)))
 			f.info.letSize += let.info.size
-			f.info.size += f.sizeOfExprs(c, let.let.Exprs) + let.info.loadSize
+			f.info.size += f.sizeOfExprs(c, let.let.Exprs) + uint64(len(c.storeFrame(let)))
 		}
 
-		f.info.size += f.info.letSize
+		f.info.size += uint64(len(c.enterFrame(f.info.letSize)))
 	}
 
 	if f.info.asm {
-		f.info.size += uint64(len(f.fun.Block.Exprs))
+		_, size := f.asmInsts(c)
+		f.info.size += size
 	} else {
 		f.info.size += f.sizeOfExprs(c, f.fun.Block.Exprs)
 	}
 
+	f.info.size += f.sizeOfLeave(c)
 	if !f.info.inline {
 		f.info.size += 1
 		f.info.pos = c.getNextPos(f.info.size)
 	}
 }
 
+func (f *Fun) hasFrame() bool {
+	return len(f.fun.Block.Lets) != 0
+}
+
+func (f *Fun) sizeOfLeave(c *Ctx) uint64 {
+	if !f.hasFrame() || f.makeFunIdent(c) == c.start {
+		return 0
+	}
+	return uint64(len(c.leaveFrame(0)))
+}
+
+func (f *Fun) leave(c *Ctx) []uint8 {
+	if !f.hasFrame() || f.makeFunIdent(c) == c.start {
+		return []uint8{}
+	}
+	return c.leaveFrame(f.info.letSize)
+}
+
 func (f *Fun) sizeOfExprs(c *Ctx, exprs []parser.Expr) uint64 {
 	var size uint64 = 0
 	for i := 0; i < len(exprs); i++ {
@@ -229,29 +550,27 @@ func (f *Fun) sizeOfExprs(c *Ctx, exprs []parser.Expr) uint64 {
 		wrap := expr.AsWrap()
 		addr := expr.AsAddr()
 		ret := expr.AsReturn()
+		hole := expr.AsHole()
 		if ident != nil {
-			ident := ident.Content
-			let := f.info.lets[ident]
+			let := f.getLet(c, ident.Content)
 			if let == nil {
-				let = c.lets[ident]
-				if let == nil {
-					panic(fmt.Sprintf("unknown ident '%s'", ident))
-				}
-				let.getInfo(c)
+				c.errorf(ident.Span, "unknown ident '%s'", ident.Content)
+				continue
 			}
-			size += let.info.loadSize
+			size += let.getInfo(c).loadSize
 		} else if call != nil {
 			ident := c.makeFunIdent(call.Ident.Content, call.Inputs, call.Outputs)
 			fun := c.funs[ident]
 			if fun == nil {
-				panic(fmt.Sprintf("unknown fun '%s'", ident))
+				c.errorf(call.Span, "unknown fun '%s'", ident)
+				continue
 			}
 			if fun.makeFunIdent(c) == c.start {
-				panic(fmt.Sprintf("fun '%s' can't call '%s'", f.makeFunIdent(c), c.start))
+				c.errorf(call.Span, "fun '%s' can't call '%s'", f.makeFunIdent(c), c.start)
 			}
 			finfo := fun.getInfo(c)
 			if finfo.unsafe && !(f.info.unsafe || f.info.safe) {
-				panic(fmt.Sprintf("fun '%s' can't call unsafe fun '%s'", f.makeFunIdent(c), ident))
+				c.errorf(call.Span, "fun '%s' can't call unsafe fun '%s'", f.makeFunIdent(c), ident)
 			}
 			if finfo.inline {
 				size += finfo.size
@@ -273,42 +592,44 @@ func (f *Fun) sizeOfExprs(c *Ctx, exprs []parser.Expr) uint64 {
 			size += f.sizeOfExprs(c, while.Exprs) + 1 + 8
 		} else if unwrap != nil {
 			if !(f.info.unsafe || f.info.safe) {
-				panic(fmt.Sprintf("fun '%s' can't call unsafe .unwrap", f.makeFunIdent(c)))
+				c.errorf(unwrap.Span, "fun '%s' can't call unsafe .unwrap", f.makeFunIdent(c))
 			}
 		} else if wrap != nil {
 			if !(f.info.unsafe || f.info.safe) {
-				panic(fmt.Sprintf("fun '%s' can't call unsafe .wrap", f.makeFunIdent(c)))
+				c.errorf(wrap.Span, "fun '%s' can't call unsafe .wrap", f.makeFunIdent(c))
 			}
 		} else if addr != nil {
 			if !(f.info.unsafe || f.info.safe) {
-				panic(fmt.Sprintf("fun '%s' can't call unsafe .addr", f.makeFunIdent(c)))
+				c.errorf(addr.Span, "fun '%s' can't call unsafe .addr", f.makeFunIdent(c))
 			}
 			if addr.Ident != nil {
-				ident := addr.Ident.Content
-				if f.info.lets[ident] == nil {
-					let := c.lets[ident]
-					if let == nil {
-						panic(fmt.Sprintf("unknown ident '%s'", ident))
-					}
+				let := f.getLet(c, addr.Ident.Content)
+				if let == nil {
+					c.errorf(addr.Span, "unknown ident '%s'", addr.Ident.Content)
+				} else {
 					let.getInfo(c)
 				}
+				if f.info.lets[addr.Ident.Content] != nil {
+					size += uint64(len(c.frameAddr(0)))
+					continue
+				}
 			} else {
 				call := addr.Call
 				ident := c.makeFunIdent(call.Ident.Content, call.Inputs, call.Outputs)
 				fun := c.funs[ident]
 				if fun == nil {
-					panic(fmt.Sprintf("unknown fun '%s' in '%s'", ident, f.makeFunIdent(c)))
-				}
-				if fun.getInfo(c).inline {
-					panic(fmt.Sprintf("can't get .addr of inline fun '%s' in '%s'", ident, f.makeFunIdent(c)))
+					c.errorf(addr.Span, "unknown fun '%s' in '%s'", ident, f.makeFunIdent(c))
+				} else if fun.getInfo(c).inline {
+					c.errorf(addr.Span, "can't get .addr of inline fun '%s' in '%s'", ident, f.makeFunIdent(c))
 				}
 			}
 			size += 1 + 8
 		} else if ret != nil {
 			if f.info.inline {
-				panic(fmt.Sprintf("can't .return in inline fun '%s'", f.makeFunIdent(c)))
+				c.errorf(ret.Span, "can't .return in inline fun '%s'", f.makeFunIdent(c))
 			}
-			size += 1
+			size += 1 + f.sizeOfLeave(c)
+		} else if hole != nil {
 		} else {
 			panic("unreachable")
 		}
@@ -327,6 +648,14 @@ type Let struct {
 	let  *parser.Let
 }
 
+func (f *Fun) getLet(c *Ctx, ident string) *Let {
+	let := f.info.lets[ident]
+	if let == nil {
+		let = c.lets[ident]
+	}
+	return let
+}
+
 func (l *Let) getInfo(c *Ctx) *LInfo {
 	if l.info == nil {
 		l.comInfo(c)
@@ -342,8 +671,10 @@ func (l *Let) comInfo(c *Ctx) {
 	l.info = &LInfo{}
 	l.info.size = uint64(l.let.Typ.Size(c.types))
 	l.info.loadSize = 0
-	for range l.let.Typ.LoadSizes(c.types) {
-		l.info.loadSize += 1 + 8
+	for _, size := range l.let.Typ.LoadSizes(c.types) {
+		if size != 0 {
+			l.info.loadSize += 1 + 8
+		}
 	}
 }
 
@@ -352,35 +683,54 @@ type Type struct {
 }
 
 type Ctx struct {
-	size  uint64
-	strs  string
-	lets  map[string]*Let
-	funs  map[string]*Fun
-	types *parser.Types
-	start string
-}
+	ctx         context.Context
+	fs          fs.FS
+	searchPaths []string
+	fp          uint64
+	scratch     uint64
+	loaded      map[string]*module
+	modules     []*module
+	diags       []diag.Diagnostic
+	size        uint64
+	strs        string
+	lets        map[string]*Let
+	funs        map[string]*Fun
+	types       *parser.Types
+	start       string
+	insts       *instSet
+	lines       []Line
+	stacks      []Stack
 
-func initialBytes() ([]uint8, int) {
-	return []uint8{220, 0, 0, 0, 0, 0, 0, 0, 0}, 1
+	backtraceLines bool
+	tracing        bool
 }
 
-func (c *Ctx) compile() []uint8 {
-	bytes, saddr := initialBytes()
-	c.size = uint64(len(bytes))
-
+func (c *Ctx) check() (*Fun, []*Fun) {
 	c.start = c.makeFunIdent(".start", []parser.Typ{parser.STRING}, []parser.Typ{})
 	start := c.funs[c.start]
 
 	if start == nil {
-		panic(fmt.Sprintln("missing .start(string:) fun"))
+		c.errorf(lexer.Span{}, "missing .start(string:) fun")
+		return nil, nil
 	}
 
 	sinfo := start.getInfo(c)
 	if sinfo.inline {
-		panic(fmt.Sprintln(".start(string:) can't be an inline fun"))
+		c.errorf(start.fun.Ident.Span, ".start(string:) can't be an inline fun")
 	}
 	if !sinfo.unsafe {
-		panic(fmt.Sprintln(".start(string:) needs to be unsafe"))
+		c.errorf(start.fun.Ident.Span, ".start(string:) needs to be unsafe")
+	}
+
+	lets := []*Let{}
+	for _, let := range c.lets {
+		lets = append(lets, let)
+	}
+	sort.Slice(lets, func(i, j int) bool {
+		return lets[i].let.Ident.Content < lets[j].let.Ident.Content
+	})
+	for _, l := range lets {
+		l.staticCheck(c)
 	}
 
 	funs := []*Fun{}
@@ -401,22 +751,58 @@ func (c *Ctx) compile() []uint8 {
 		}
 	}
 
+	uncalled := []*Fun{}
+	for _, f := range funs {
+		if f.info == nil && f.hasHole() {
+			uncalled = append(uncalled, f)
+		}
+	}
+	sort.Slice(uncalled, func(i, j int) bool {
+		return uncalled[i].makeFunIdent(c) < uncalled[j].makeFunIdent(c)
+	})
+	for _, f := range uncalled {
+		func() {
+			defer c.catch()
+			f.typeCheck(c, f.getInfo(c).simpleTypeCheck)
+		}()
+	}
+	return start, funs
+}
+
+func (c *Ctx) compile() []uint8 {
+	bytes := c.frameSetup(0)
+	c.size = uint64(len(bytes))
+
+	start, funs := c.check()
+	if diag.HasErrors(c.diags) {
+		return nil
+	}
+	sinfo := start.info
+
 	lets := []*Let{}
 	for _, let := range c.lets {
 		lets = append(lets, let)
 	}
+	sort.Slice(lets, func(i, j int) bool {
+		return lets[i].let.Ident.Content < lets[j].let.Ident.Content
+	})
 
 	for _, l := range lets {
 		if l.info != nil {
 			l.info.pos = c.getNextPos(l.info.size)
 		}
 	}
+	c.fp = c.getNextPos(8)
+	c.scratch = c.getNextPos(16)
 
-	putUvarint(bytes[saddr:saddr+8], sinfo.pos)
+	copy(bytes, c.frameSetup(sinfo.pos))
 
 	for _, f := range funs {
 		if f.info != nil && !f.info.inline {
-			bytes = append(bytes, f.compile(c)...)
+			mark := len(c.lines)
+			code := f.compile(c)
+			c.addLine(0, code, f.fun.Span, f)
+			bytes = c.emit(bytes, mark, code)
 		}
 	}
 
@@ -432,36 +818,54 @@ func (c *Ctx) compile() []uint8 {
 			bytes = append(bytes, l.staticCompile(c)...)
 		}
 	}
+	bytes = append(bytes, make([]uint8, 8+16)...)
+	bytes = append(bytes, []uint8(c.strs)...)
 
-	return append(bytes, []uint8(c.strs)...)
+	if l := c.lets[".backtrace"]; l != nil && l.info != nil {
+		if l.info.size != 8 {
+			c.errorf(l.let.Span, "the let '.backtrace' needs to be an u64")
+			return nil
+		}
+		putUvarint(bytes[l.info.pos:l.info.pos+8], uint64(len(bytes)))
+		bytes = append(bytes, c.backtrace(bytes, uint64(len(bytes)))...)
+	}
+	return bytes
 }
 
-func (l *Let) staticCompile(c *Ctx) []uint8 {
+func (l *Let) staticCheck(c *Ctx) {
 	if len(l.let.Exprs) != 1 {
-		panic(fmt.Sprintf("let '%s' has to haves exactly one expr", l.let.Ident.Content))
+		c.errorf(l.let.Span, "let '%s' has to haves exactly one expr", l.let.Ident.Content)
+		return
 	}
 	expr := l.let.Exprs[0]
-	var bytes []uint8
-	number := expr.AsNumber()
-	str := expr.AsString()
-	if number != nil {
-		num, err := strconv.ParseUint(number.Content, number.Base, number.Size*8)
-		bytes = make([]uint8, l.info.size)
-		if err != nil {
-			panic(fmt.Sprintf("unable to convert '%s' to a number", number.Content))
-		}
-		putUvarint(bytes, num)
-	} else if str != nil {
-		c.pushStr(str.Content)
-		ptr := c.getStr(str.Content)
-		buf := []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
-		putUvarint(buf[0:8], ptr)
-		putUvarint(buf[8:], uint64(len(str.Content)))
-		bytes = append(bytes, buf...)
+	var typ parser.Typ
+	if number := expr.AsNumber(); number != nil {
+		typ = number.Typ
+	} else if expr.AsString() != nil {
+		typ = parser.STRING
 	} else {
-		panic(fmt.Sprintf("let '%s' can only have a string or number expr", l.let.Ident.Content))
+		c.errorf(l.let.Span, "let '%s' can only have a string or number expr", l.let.Ident.Content)
+		return
 	}
-	return bytes
+	if typ != nil && typ.String(c.types) != l.let.Typ.String(c.types) {
+		c.errorf(expr.GetSpan(), "the let '%s' is a '%s' but its value is a '%s'", l.let.Ident.Content, l.let.Typ.String(c.types), typ.String(c.types))
+	}
+}
+
+func (l *Let) staticCompile(c *Ctx) []uint8 {
+	expr := l.let.Exprs[0]
+	if number := expr.AsNumber(); number != nil {
+		bytes := make([]uint8, l.info.size)
+		c.putNumber(bytes, number)
+		return bytes
+	}
+	str := expr.AsString()
+	c.pushStr(str.Content)
+	ptr := c.getStr(str.Content)
+	buf := []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	putUvarint(buf[0:8], ptr)
+	putUvarint(buf[8:], uint64(len(str.Content)))
+	return buf
 }
 
 func (c *Ctx) getNextPos(size uint64) uint64 {
@@ -486,40 +890,27 @@ func (f *Fun) compile(c *Ctx) []uint8 {
 		sort.Slice(lets, func(i, j int) bool {
 			return lets[i].info.pos < lets[j].info.pos
 		})
+		if f.hasFrame() {
+			bytes = append(bytes, c.enterFrame(f.info.letSize)...)
+		}
 		for _, l := range lets {
-			bytes = append(bytes, f.compileExprs(c, l.let.Exprs)...)
-			buf := []uint8{}
-			pos := f.info.pos + f.info.size - f.info.letSize + l.info.pos
-			for off, size := range l.let.Typ.LoadSizes(c.types) {
-				len := len(buf)
-				switch size {
-				case 0:
-					continue
-				case 1:
-					buf = append(buf, 235, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 2:
-					buf = append(buf, 236, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 4:
-					buf = append(buf, 237, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 8:
-					buf = append(buf, 238, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 16:
-					buf = append(buf, 239, 0, 0, 0, 0, 0, 0, 0, 0)
-				default:
-					panic("invalid size")
-				}
-				putUvarint(buf[len+1:], pos+l.info.size-8-uint64(8*off))
-			}
-			bytes = append(bytes, buf...)
+			mark := len(c.lines)
+			start := len(bytes)
+			bytes = c.emit(bytes, mark, f.compileExprs(c, l.let.Exprs))
+			bytes = append(bytes, c.storeFrame(l)...)
+			c.addLine(start, bytes, l.let.Span, f)
 		}
 
-		bytes = append(bytes, f.compileExprs(c, f.fun.Block.Exprs)...)
+		mark := len(c.lines)
+		bytes = c.emit(bytes, mark, f.compileExprs(c, f.fun.Block.Exprs))
 		if f.makeFunIdent(c) == c.start {
 			bytes = append(bytes, 1)
-		} else if !f.info.inline {
-			bytes = append(bytes, 3)
+		} else {
+			bytes = append(bytes, f.leave(c)...)
+			if !f.info.inline {
+				bytes = append(bytes, 3)
+			}
 		}
-		bytes = append(bytes, make([]uint8, f.info.letSize, f.info.letSize)...)
 	}
 	return bytes
 }
@@ -528,6 +919,8 @@ func (f *Fun) compileExprs(c *Ctx, exprs []parser.Expr) []uint8 {
 	bytes := []uint8{}
 	for i := 0; i < len(exprs); i++ {
 		expr := exprs[i]
+		start := len(bytes)
+		mark := len(c.lines)
 		ident := expr.AsIdent()
 		call := expr.AsCall()
 		number := expr.AsNumber()
@@ -538,43 +931,28 @@ func (f *Fun) compileExprs(c *Ctx, exprs []parser.Expr) []uint8 {
 		wrap := expr.AsWrap()
 		addr := expr.AsAddr()
 		ret := expr.AsReturn()
+		hole := expr.AsHole()
 		if ident != nil {
 			ident := ident.Content
-			let := f.info.lets[ident]
-			var pos uint64
-			if let == nil {
-				let = c.lets[ident]
-				pos = let.info.pos
+			if let := f.info.lets[ident]; let != nil {
+				bytes = append(bytes, c.loadFrame(let)...)
 			} else {
-				pos = f.info.pos + f.info.size - f.info.letSize + let.info.pos
-			}
-			buf := []uint8{}
-			for off, size := range let.let.Typ.LoadSizes(c.types) {
-				len := len(buf)
-				switch size {
-				case 0:
-					continue
-				case 1:
-					buf = append(buf, 230, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 2:
-					buf = append(buf, 231, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 4:
-					buf = append(buf, 232, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 8:
-					buf = append(buf, 233, 0, 0, 0, 0, 0, 0, 0, 0)
-				case 16:
-					buf = append(buf, 234, 0, 0, 0, 0, 0, 0, 0, 0)
-				default:
-					panic("invalid size")
+				let = c.lets[ident]
+				sizes, offs := c.offsets(let.let.Typ)
+				for i, size := range sizes {
+					if size == 0 {
+						continue
+					}
+					buf := []uint8{230 + sizeInst(size), 0, 0, 0, 0, 0, 0, 0, 0}
+					putUvarint(buf[1:], let.info.pos+offs[i])
+					bytes = append(bytes, buf...)
 				}
-				putUvarint(buf[len+1:], pos+uint64(8*off))
 			}
-			bytes = append(bytes, buf...)
 		} else if call != nil {
 			ident := c.makeFunIdent(call.Ident.Content, call.Inputs, call.Outputs)
 			fun := c.funs[ident]
 			if fun.info.inline {
-				bytes = append(bytes, fun.compile(c)...)
+				bytes = c.emit(bytes, mark, fun.compile(c))
 			} else {
 				buf := []uint8{229, 0, 0, 0, 0, 0, 0, 0, 0}
 				putUvarint(buf[1:], fun.info.pos)
@@ -582,10 +960,6 @@ func (f *Fun) compileExprs(c *Ctx, exprs []parser.Expr) []uint8 {
 
 			}
 		} else if number != nil {
-			num, err := strconv.ParseUint(number.Content, number.Base, number.Size*8)
-			if err != nil {
-				panic(fmt.Sprintf("unable to convert '%s' to a number", number.Content))
-			}
 			var buf []uint8
 			switch number.Size {
 			case 1:
@@ -596,10 +970,12 @@ func (f *Fun) compileExprs(c *Ctx, exprs []parser.Expr) []uint8 {
 				buf = []uint8{12, 0, 0, 0, 0}
 			case 8:
 				buf = []uint8{13, 0, 0, 0, 0, 0, 0, 0, 0}
+			case 16:
+				buf = []uint8{14, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 			default:
 				panic("invalid size")
 			}
-			putUvarint(buf[1:], num)
+			c.putNumber(buf[1:], number)
 			bytes = append(bytes, buf...)
 		} else if str != nil {
 			ptr := c.getStr(str.Content)
@@ -608,59 +984,70 @@ func (f *Fun) compileExprs(c *Ctx, exprs []parser.Expr) []uint8 {
 			putUvarint(buf[10:], uint64(len(str.Content)))
 			bytes = append(bytes, buf...)
 		} else if ifel != nil {
-			bytes = append(bytes, f.compileExprs(c, ifel.Con)...)
+			bytes = c.emit(bytes, mark, f.compileExprs(c, ifel.Con))
 
+			mark = len(c.lines)
+			els := f.compileExprs(c, ifel.Else)
 			buf := []uint8{226, 0, 0, 0, 0, 0, 0, 0, 0}
-			putUvarint(buf[1:], f.sizeOfExprs(c, ifel.Else)+18)
+			putUvarint(buf[1:], uint64(len(els))+18)
 			bytes = append(bytes, buf...)
-			bytes = append(bytes, f.compileExprs(c, ifel.Else)...)
+			bytes = c.emit(bytes, mark, els)
 
+			mark = len(c.lines)
+			then := f.compileExprs(c, ifel.Exprs)
 			buf = []uint8{221, 0, 0, 0, 0, 0, 0, 0, 0}
-			putUvarint(buf[1:], f.sizeOfExprs(c, ifel.Exprs)+9)
+			putUvarint(buf[1:], uint64(len(then))+9)
 			bytes = append(bytes, buf...)
-			bytes = append(bytes, f.compileExprs(c, ifel.Exprs)...)
+			bytes = c.emit(bytes, mark, then)
 		} else if while != nil {
-			bytes = append(bytes, f.compileExprs(c, while.Con)...)
+			con := f.compileExprs(c, while.Con)
+			bytes = c.emit(bytes, mark, con)
 
 			buf := []uint8{226, 0, 0, 0, 0, 0, 0, 0, 0}
 			putUvarint(buf[1:], 18)
 			bytes = append(bytes, buf...)
 
+			mark = len(c.lines)
+			body := f.compileExprs(c, while.Exprs)
 			buf = []uint8{221, 0, 0, 0, 0, 0, 0, 0, 0}
-			putUvarint(buf[1:], f.sizeOfExprs(c, while.Exprs)+18)
+			putUvarint(buf[1:], uint64(len(body))+18)
 			bytes = append(bytes, buf...)
 
-			bytes = append(bytes, f.compileExprs(c, while.Exprs)...)
+			bytes = c.emit(bytes, mark, body)
 
 			buf = []uint8{222, 0, 0, 0, 0, 0, 0, 0, 0}
-			putUvarint(buf[1:], f.sizeOfExprs(c, while.Exprs)+f.sizeOfExprs(c, while.Con)+18)
+			putUvarint(buf[1:], uint64(len(body)+len(con))+18)
 			bytes = append(bytes, buf...)
 		} else if unwrap != nil {
 		} else if wrap != nil {
 		} else if addr != nil {
 			var pos uint64
+			var let *Let
 			if addr.Ident != nil {
 				ident := addr.Ident.Content
-				let := f.info.lets[ident]
-				if let == nil {
-					let = c.lets[ident]
-					pos = let.info.pos
-				} else {
-					pos = f.info.pos + f.info.size - f.info.letSize + let.info.pos
+				if let = f.info.lets[ident]; let == nil {
+					pos = c.lets[ident].info.pos
 				}
 			} else {
 				call = addr.Call
 				ident := c.makeFunIdent(call.Ident.Content, call.Inputs, call.Outputs)
 				pos = c.funs[ident].info.pos
 			}
-			buf := []uint8{13, 0, 0, 0, 0, 0, 0, 0, 0}
-			putUvarint(buf[1:], pos)
-			bytes = append(bytes, buf...)
+			if let != nil {
+				bytes = append(bytes, c.frameAddr(let.info.pos)...)
+			} else {
+				buf := []uint8{13, 0, 0, 0, 0, 0, 0, 0, 0}
+				putUvarint(buf[1:], pos)
+				bytes = append(bytes, buf...)
+			}
 		} else if ret != nil {
+			bytes = append(bytes, f.leave(c)...)
 			bytes = append(bytes, 3)
+		} else if hole != nil {
 		} else {
 			panic("unreachable")
 		}
+		c.addLine(start, bytes, expr.GetSpan(), f)
 	}
 
 	return bytes
@@ -0,0 +1,312 @@
+package compiler
+
+import (
+	"bootstrap/diag"
+	"bootstrap/vm"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const prelude = "import \"core/prelude.mvm\";\n\n"
+
+func files(srcs map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, src := range srcs {
+		fsys[name] = &fstest.MapFile{Data: []uint8(src)}
+	}
+	return fsys
+}
+
+func compileFiles(t *testing.T, srcs map[string]string) (*Result, []diag.Diagnostic) {
+	t.Helper()
+	return Compile(context.Background(), Options{Entry: "main.mvm", FS: files(srcs), Builtin: os.DirFS("..")})
+}
+
+func compileSrc(t *testing.T, src string) (*Result, []diag.Diagnostic) {
+	t.Helper()
+	return compileFiles(t, map[string]string{"main.mvm": src})
+}
+
+func messages(diags []diag.Diagnostic) string {
+	msgs := []string{}
+	for _, d := range diags {
+		msgs = append(msgs, d.String())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func mustCompile(t *testing.T, src string) *Result {
+	t.Helper()
+	res, diags := compileSrc(t, src)
+	if res == nil || diag.HasErrors(diags) {
+		t.Fatalf("compile failed:\n%s", messages(diags))
+	}
+	return res
+}
+
+func expectDiags(t *testing.T, name string, diags []diag.Diagnostic, want string) {
+	t.Helper()
+	if got := messages(diags); got != want {
+		t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+func run(t *testing.T, res *Result) ([]uint8, string) {
+	t.Helper()
+	out := &bytes.Buffer{}
+	m := vm.New(res.Bytes, nil)
+	m.Stdin = strings.NewReader("")
+	m.Stdout = out
+	if err := m.Run(); err != nil {
+		t.Fatalf("run: %s\noutput:\n%s", err, out)
+	}
+	return m.Stack(), strings.TrimPrefix(out.String(), "args: ''\n\n")
+}
+
+func stackOf(t *testing.T, decls string, body string) []uint8 {
+	t.Helper()
+	stack, _ := run(t, mustCompile(t, prelude+decls+"\nfun{unsafe} main(:) {\n    "+body+"\n    .asm.halt(:!)\n}\n"))
+	return stack
+}
+
+func symbol(res *Result, name string) *Symbol {
+	for i := range res.Symbols {
+		if res.Symbols[i].Name == name {
+			return &res.Symbols[i]
+		}
+	}
+	return nil
+}
+
+func TestCompileResult(t *testing.T) {
+	res := mustCompile(t, prelude+"let greeting: string \"hello\\n\";\n\nfun{safe} main(:) {\n    greeting print(string:)\n}\n")
+	for _, name := range []string{"main(:)", ".start(string:)", "print(string:)"} {
+		sym := symbol(res, name)
+		if sym == nil || sym.Kind != FUN || sym.Size == 0 || sym.Addr+sym.Size > uint64(len(res.Bytes)) {
+			t.Errorf("fun symbol '%s' is %+v", name, sym)
+		}
+	}
+	if sym := symbol(res, "greeting"); sym == nil || sym.Kind != LET || sym.Size != 16 {
+		t.Errorf("let symbol 'greeting' is %+v", sym)
+	}
+	if sym := symbol(res, "main(:)"); sym != nil && sym.Span.String() != "main.mvm:5:1" {
+		t.Errorf("main is declared at %s", sym.Span)
+	}
+	strs := res.Strings
+	if !strings.Contains(strs.Data, "hello\n") || string(res.Bytes[strs.Addr:strs.Addr+uint64(len(strs.Data))]) != strs.Data {
+		t.Errorf("strings %q aren't at 0x%x", strs.Data, strs.Addr)
+	}
+	if _, out := run(t, res); out != "hello\n" {
+		t.Errorf("output %q", out)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"syntax", "fun main(:) {", "main.mvm:1:14: error: unexpected token 'EOF' expected '}'"},
+		{"no start", "fun main(:) {}\n", "error: missing .start(string:) fun"},
+		{"unknown fun", prelude + "fun{safe} main(:) {\n    nope(:)\n}\n", "main.mvm:4:5: error: unknown fun 'nope(:)'"},
+		{"two errors", prelude + "fun{safe} main(:) {\n    other(:) nope(:)\n}\n\nfun{safe} other(:) {\n    never(:)\n}\n", "main.mvm:8:5: error: unknown fun 'never(:)'\nmain.mvm:4:14: error: unknown fun 'nope(:)'"},
+	}
+	for _, test := range tests {
+		res, diags := compileSrc(t, test.src)
+		if res != nil {
+			t.Errorf("%s: compiled", test.name)
+		}
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
+
+func TestCompileConcurrent(t *testing.T) {
+	src, err := os.ReadFile("../../fib.mvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mustCompile(t, string(src)).Bytes
+	results := make(chan []uint8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			res, _ := Compile(context.Background(), Options{Entry: "main.mvm", FS: files(map[string]string{"main.mvm": string(src)}), Builtin: os.DirFS("..")})
+			if res == nil {
+				results <- nil
+				return
+			}
+			results <- res.Bytes
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if got := <-results; !bytes.Equal(got, want) {
+			t.Errorf("concurrent compile %d differs", i)
+		}
+	}
+}
+
+func TestCompileCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, diags := Compile(ctx, Options{Entry: "main.mvm", FS: files(map[string]string{"main.mvm": prelude}), Builtin: os.DirFS("..")})
+	if res != nil {
+		t.Error("compiled with a canceled context")
+	}
+	expectDiags(t, "canceled", diags, "error: compilation canceled: context canceled")
+}
+
+const libMain = "import \"core/prelude.mvm\";\nimport \"lib/a.mvm\";\n\nfun{safe} main(:) {\n    a(:)\n}\n"
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		srcs  map[string]string
+		out   string
+		want  string
+	}{
+		{
+			"relative to the importing file",
+			"main.mvm",
+			map[string]string{
+				"main.mvm":  libMain,
+				"lib/a.mvm": "import \"b.mvm\";\n\nfun{safe} a(:) {\n    b(:)\n}\n",
+				"lib/b.mvm": "import \"core/prelude.mvm\";\n\nfun{safe} b(:) {\n    \"b\\n\" print(string:)\n}\n",
+			},
+			"b\n", "",
+		},
+		{
+			"entry in a directory",
+			"src/main.mvm",
+			map[string]string{
+				"src/main.mvm":  libMain,
+				"src/lib/a.mvm": "import \"../../b.mvm\";\n\nfun{safe} a(:) {\n    b(:)\n}\n",
+				"b.mvm":         "import \"core/prelude.mvm\";\n\nfun{safe} b(:) {\n    \"b\\n\" print(string:)\n}\n",
+			},
+			"b\n", "",
+		},
+		{
+			"cycle",
+			"main.mvm",
+			map[string]string{
+				"main.mvm":  libMain,
+				"lib/a.mvm": "import \"../main.mvm\";\n\nfun{safe} a(:) {\n    \"a\\n\" print(string:)\n}\n",
+			},
+			"a\n", "",
+		},
+		{
+			"missing",
+			"main.mvm",
+			map[string]string{"main.mvm": libMain},
+			"", "main.mvm:2:1: error: unable to find import 'lib/a.mvm'\n\tnote: looked in '.'\n\tnote: looked in '<builtin>'",
+		},
+		{
+			"missing entry",
+			"nope.mvm",
+			map[string]string{"main.mvm": libMain},
+			"", "error: unable to read 'nope.mvm'",
+		},
+	}
+	for _, test := range tests {
+		res, diags := Compile(context.Background(), Options{Entry: test.entry, FS: files(test.srcs), Builtin: os.DirFS("..")})
+		expectDiags(t, test.name, diags, test.want)
+		if res == nil {
+			continue
+		}
+		if _, out := run(t, res); out != test.out {
+			t.Errorf("%s: output %q, want %q", test.name, out, test.out)
+		}
+	}
+}
+
+func TestImportsFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	srcs := map[string]string{
+		"src/main.mvm":  libMain,
+		"src/lib/a.mvm": "import \"core/prelude.mvm\";\n\nfun{safe} a(:) {\n    \"a\\n\" print(string:)\n}\n",
+	}
+	for name, src := range srcs {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []uint8(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, diags := Compile(context.Background(), Options{Entry: filepath.Join(dir, "src", "main.mvm"), Builtin: os.DirFS("..")})
+	if res == nil {
+		t.Fatalf("compile failed:\n%s", messages(diags))
+	}
+	if _, out := run(t, res); out != "a\n" {
+		t.Errorf("output %q", out)
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	lib := "fun{safe} lib(:) {\n    \"%s\\n\" print(string:)\n}\n"
+	main := "import \"core/prelude.mvm\";\nimport \"x/lib.mvm\";\n\nfun{safe} main(:) {\n    lib(:)\n}\n"
+	tests := []struct {
+		name  string
+		paths []string
+		srcs  map[string]string
+		out   string
+		want  string
+	}{
+		{
+			"search path",
+			[]string{"vendor"},
+			map[string]string{"src/main.mvm": main, "vendor/x/lib.mvm": fmt.Sprintf(lib, "vendor")},
+			"vendor\n", "",
+		},
+		{
+			"importing directory first",
+			[]string{"vendor"},
+			map[string]string{"src/main.mvm": main, "src/x/lib.mvm": fmt.Sprintf(lib, "src"), "vendor/x/lib.mvm": fmt.Sprintf(lib, "vendor")},
+			"src\n", "",
+		},
+		{
+			"in order",
+			[]string{"first", "second"},
+			map[string]string{"src/main.mvm": main, "first/x/lib.mvm": fmt.Sprintf(lib, "first"), "second/x/lib.mvm": fmt.Sprintf(lib, "second")},
+			"first\n", "",
+		},
+		{
+			"every directory tried",
+			[]string{"first", "second"},
+			map[string]string{"src/main.mvm": main},
+			"", "src/main.mvm:2:1: error: unable to find import 'x/lib.mvm'\n\tnote: looked in 'src'\n\tnote: looked in 'first'\n\tnote: looked in 'second'\n\tnote: looked in '<builtin>'",
+		},
+	}
+	for _, test := range tests {
+		res, diags := Compile(context.Background(), Options{Entry: "src/main.mvm", SearchPaths: test.paths, FS: files(test.srcs), Builtin: os.DirFS("..")})
+		expectDiags(t, test.name, diags, test.want)
+		if res == nil {
+			continue
+		}
+		if _, out := run(t, res); out != test.out {
+			t.Errorf("%s: output %q, want %q", test.name, out, test.out)
+		}
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	srcs := map[string]string{
+		"a/b/c/main.mvm": prelude + "fun{safe} main(:) {\n    \"core\\n\" print(string:)\n}\n",
+	}
+	res, diags := Compile(context.Background(), Options{Entry: "a/b/c/main.mvm", FS: files(srcs), Builtin: os.DirFS("..")})
+	if res == nil {
+		t.Fatalf("compile failed:\n%s", messages(diags))
+	}
+	if sym := symbol(res, "print(string:)"); sym == nil || sym.Span.File != BuiltinDir+"/core/string.mvm" {
+		t.Errorf("print comes from %+v", sym)
+	}
+	_, diags = Compile(context.Background(), Options{Entry: "a/b/c/main.mvm", FS: files(srcs)})
+	expectDiags(t, "no builtin", diags, "a/b/c/main.mvm:1:1: error: unable to find import 'core/prelude.mvm'\n\tnote: looked in 'a/b/c'")
+}
@@ -0,0 +1,238 @@
+package compiler
+
+import (
+	"bootstrap/isa"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Inst struct {
+	Addr uint64
+	Code uint8
+	Name string
+	Imm  []uint8
+}
+
+func (s *instSet) tables() ([256]string, [256]int) {
+	var names [256]string
+	var sizes [256]int
+	for name, code := range s.insts {
+		names[code] = name
+	}
+	for name, imm := range s.immInsts {
+		names[imm.code] = name
+		sizes[imm.code] = imm.size
+	}
+	return names, sizes
+}
+
+var instNames, instImms = builtinInsts.tables()
+
+func decodeInst(names *[256]string, sizes *[256]int, bytes []uint8, addr uint64) (Inst, bool) {
+	if addr >= uint64(len(bytes)) {
+		return Inst{}, false
+	}
+	code := bytes[addr]
+	end := addr + 1 + uint64(sizes[code])
+	if names[code] == "" || end > uint64(len(bytes)) {
+		return Inst{}, false
+	}
+	return Inst{Addr: addr, Code: code, Name: names[code], Imm: bytes[addr+1 : end]}, true
+}
+
+func DecodeInst(bytes []uint8, addr uint64) (Inst, bool) {
+	return decodeInst(&instNames, &instImms, bytes, addr)
+}
+
+func (i Inst) Size() uint64 {
+	return 1 + uint64(len(i.Imm))
+}
+
+func (i Inst) Value() *big.Int {
+	buf := make([]uint8, len(i.Imm))
+	for j := range i.Imm {
+		buf[len(buf)-1-j] = i.Imm[j]
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+func (i Inst) Target() (uint64, bool) {
+	val := i.Value().Uint64()
+	switch i.Code {
+	case 220, 225, 229:
+		return val, true
+	case 221, 226:
+		return i.Addr + val, true
+	case 222, 227:
+		return i.Addr - val, true
+	}
+	return 0, false
+}
+
+type disasm struct {
+	w       io.Writer
+	bytes   []uint8
+	symbols []Symbol
+	strs    Strings
+	names   [256]string
+	sizes   [256]int
+}
+
+func Disassemble(w io.Writer, bytes []uint8, symbols []Symbol, strs Strings, ext []isa.Inst) error {
+	insts, err := newInstSet(ext)
+	if err != nil {
+		return err
+	}
+	syms := append([]Symbol{}, symbols...)
+	sort.SliceStable(syms, func(i, j int) bool {
+		return syms[i].Addr < syms[j].Addr
+	})
+	if strs.Addr == 0 || strs.Addr > uint64(len(bytes)) {
+		strs = Strings{Addr: uint64(len(bytes))}
+	}
+	d := &disasm{w: w, bytes: bytes, symbols: syms, strs: strs}
+	d.names, d.sizes = insts.tables()
+	return d.run()
+}
+
+func (d *disasm) symAt(addr uint64) (Symbol, bool) {
+	for _, sym := range d.symbols {
+		if addr >= sym.Addr && addr < sym.Addr+sym.Size {
+			return sym, true
+		}
+	}
+	return Symbol{}, false
+}
+
+func (d *disasm) describe(addr uint64) string {
+	if sym, ok := d.symAt(addr); ok {
+		if addr == sym.Addr {
+			return sym.Name
+		}
+		return fmt.Sprintf("%s+0x%x", sym.Name, addr-sym.Addr)
+	}
+	if addr >= d.strs.Addr && addr < uint64(len(d.bytes)) {
+		return fmt.Sprintf(".strings+0x%x", addr-d.strs.Addr)
+	}
+	return ""
+}
+
+func (d *disasm) next(addr uint64) uint64 {
+	end := d.strs.Addr
+	for _, sym := range d.symbols {
+		if sym.Addr > addr && sym.Addr < end {
+			end = sym.Addr
+		}
+	}
+	return end
+}
+
+func (d *disasm) line(addr uint64, text string, note string) error {
+	comment := fmt.Sprintf("; 0x%04x", addr)
+	if note != "" {
+		comment += " " + note
+	}
+	_, err := fmt.Fprintf(d.w, "%-40s %s\n", text, comment)
+	return err
+}
+
+func (d *disasm) data(addr uint64, end uint64) error {
+	for addr < end {
+		n := end - addr
+		if n > 16 {
+			n = 16
+		}
+		hex := []string{}
+		for _, b := range d.bytes[addr : addr+n] {
+			hex = append(hex, fmt.Sprintf("0x%02x", b))
+		}
+		if err := d.line(addr, "    .data "+strings.Join(hex, " "), ""); err != nil {
+			return err
+		}
+		addr += n
+	}
+	return nil
+}
+
+func (d *disasm) inst(inst Inst) error {
+	text := "    " + inst.Name
+	note := ""
+	if len(inst.Imm) != 0 {
+		text += fmt.Sprintf(" 0x%x", inst.Value())
+	}
+	if target, ok := inst.Target(); ok {
+		desc := d.describe(target)
+		if sym, ok := d.symAt(target); ok && sym.Addr == target && sym.Kind == FUN && (inst.Code == 220 || inst.Code == 229) {
+			text = "    " + inst.Name + " " + sym.Name
+		} else if desc != "" {
+			note = fmt.Sprintf("-> 0x%04x <%s>", target, desc)
+		} else {
+			note = fmt.Sprintf("-> 0x%04x", target)
+		}
+	} else if inst.Code == 13 || (inst.Code >= 230 && inst.Code < 240) {
+		if desc := d.describe(inst.Value().Uint64()); desc != "" {
+			note = "<" + desc + ">"
+		}
+	}
+	return d.line(inst.Addr, text, note)
+}
+
+func (d *disasm) run() error {
+	code := true
+	var addr uint64
+	for addr < d.strs.Addr {
+		for _, sym := range d.symbols {
+			if sym.Addr == addr {
+				code = sym.Kind == FUN
+				if err := d.line(addr, sym.Name+":", string(sym.Kind)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		if !code {
+			end := d.next(addr)
+			if err := d.data(addr, end); err != nil {
+				return err
+			}
+			addr = end
+			continue
+		}
+		inst, ok := decodeInst(&d.names, &d.sizes, d.bytes[:d.strs.Addr], addr)
+		if !ok {
+			if err := d.data(addr, addr+1); err != nil {
+				return err
+			}
+			addr++
+			continue
+		}
+		if err := d.inst(inst); err != nil {
+			return err
+		}
+		addr += inst.Size()
+	}
+	if addr >= uint64(len(d.bytes)) {
+		return nil
+	}
+	end := uint64(len(d.bytes))
+	if n := uint64(len(d.strs.Data)); n != 0 && addr+n < end {
+		end = addr + n
+	}
+	if err := d.line(addr, ".strings:", ""); err != nil {
+		return err
+	}
+	if err := d.line(addr, "    .string "+strconv.Quote(string(d.bytes[addr:end])), ""); err != nil {
+		return err
+	}
+	if end == uint64(len(d.bytes)) {
+		return nil
+	}
+	if err := d.line(end, ".backtrace.table:", ""); err != nil {
+		return err
+	}
+	return d.data(end, uint64(len(d.bytes)))
+}
@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const fib = `fun{safe} fib(u64:u64) {
+    let n: u64;
+    if (n 2u64 <(u64,u64:bool)) {
+        n
+    } else {
+        n 1u64 -(u64,u64:u64) fib(u64:u64) n 2u64 -(u64,u64:u64) fib(u64:u64) +(u64,u64:u64)
+    }
+}
+
+let greeting: string "hi";
+
+fun{safe} main(:) {
+    greeting print(string:)
+    10u64 fib(u64:u64) drop(u64:)
+}
+`
+
+func TestDisassemble(t *testing.T) {
+	res := mustCompile(t, prelude+fib)
+	var out bytes.Buffer
+	if err := Disassemble(&out, res.Bytes, res.Symbols, res.Strings, nil); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"fun label", "fib(u64:u64):"},
+		{"call", "call_imm fib(u64:u64)"},
+		{"start", "jump_imm .start(string:)"},
+		{"let label", "greeting:"},
+		{"let ref", "<greeting>"},
+		{"frame pointer", "<.fp>"},
+		{"branch", "-> 0x"},
+		{"strings", ".strings:"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(text, test.want) {
+			t.Errorf("%s: '%s' is missing from\n%s", test.name, test.want, text)
+		}
+	}
+	if strings.Contains(text, "U64") || strings.Contains(text, "STRING") {
+		t.Errorf("types are not in source form\n%s", text)
+	}
+}
+
+func TestDisassembleData(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []uint8
+		want  string
+	}{
+		{"unknown opcode", []uint8{249}, "    .data 0xf9                           ; 0x0000\n"},
+		{"truncated immediate", []uint8{13, 249}, "    .data 0x0d                           ; 0x0000\n    .data 0xf9                           ; 0x0001\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := Disassemble(&out, test.bytes, nil, Strings{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, out.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package compiler
+
+import "bootstrap/parser"
+
+const (
+	frameStackSize = 0x10000
+	initStackSize  = 0x1000
+)
+
+func (c *Ctx) offsets(typ parser.Typ) ([]int, []uint64) {
+	sizes := typ.LoadSizes(c.types)
+	offs := make([]uint64, len(sizes))
+	var off uint64 = 0
+	for i, size := range sizes {
+		offs[i] = off
+		off += uint64(size)
+	}
+	return sizes, offs
+}
+
+func (c *Ctx) frameSetup(start uint64) []uint8 {
+	bytes := []uint8{
+		239, 0, 0, 0, 0, 0, 0, 0, 0,
+		13, 0, 0, 0, 0, 0, 0, 0, 0, 5,
+		13, 0, 0, 0, 0, 0, 0, 0, 0, 103,
+		238, 0, 0, 0, 0, 0, 0, 0, 0,
+		233, 0, 0, 0, 0, 0, 0, 0, 0,
+		13, 0, 0, 0, 0, 0, 0, 0, 0, 103, 15,
+		234, 0, 0, 0, 0, 0, 0, 0, 0,
+		220, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	putUvarint(bytes[1:9], c.scratch)
+	putUvarint(bytes[10:18], frameStackSize+initStackSize)
+	putUvarint(bytes[20:28], frameStackSize)
+	putUvarint(bytes[30:38], c.fp)
+	putUvarint(bytes[39:47], c.fp)
+	putUvarint(bytes[48:56], initStackSize)
+	putUvarint(bytes[59:67], c.scratch)
+	putUvarint(bytes[68:76], start)
+	return bytes
+}
+
+func (c *Ctx) frameMove(size uint64, inst uint8) []uint8 {
+	bytes := []uint8{233, 0, 0, 0, 0, 0, 0, 0, 0, 13, 0, 0, 0, 0, 0, 0, 0, 0, inst, 238, 0, 0, 0, 0, 0, 0, 0, 0}
+	putUvarint(bytes[1:9], c.fp)
+	putUvarint(bytes[10:18], size)
+	putUvarint(bytes[20:28], c.fp)
+	return bytes
+}
+
+func (c *Ctx) enterFrame(size uint64) []uint8 {
+	return c.frameMove(size, 113)
+}
+
+func (c *Ctx) leaveFrame(size uint64) []uint8 {
+	return c.frameMove(size, 103)
+}
+
+func (c *Ctx) frameAddr(off uint64) []uint8 {
+	bytes := []uint8{233, 0, 0, 0, 0, 0, 0, 0, 0, 13, 0, 0, 0, 0, 0, 0, 0, 0, 103}
+	putUvarint(bytes[1:9], c.fp)
+	putUvarint(bytes[10:18], off)
+	return bytes
+}
+
+func sizeInst(size int) uint8 {
+	switch size {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	case 4:
+		return 2
+	case 8:
+		return 3
+	case 16:
+		return 4
+	default:
+		panic("invalid size")
+	}
+}
+
+func (c *Ctx) loadFrame(l *Let) []uint8 {
+	bytes := []uint8{}
+	sizes, offs := c.offsets(l.let.Typ)
+	for i, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		bytes = append(bytes, c.frameAddr(l.info.pos+offs[i])...)
+		bytes = append(bytes, 210+sizeInst(size))
+	}
+	return bytes
+}
+
+func (c *Ctx) storeFrame(l *Let) []uint8 {
+	bytes := []uint8{}
+	sizes, offs := c.offsets(l.let.Typ)
+	for i := len(sizes) - 1; i >= 0; i-- {
+		if sizes[i] == 0 {
+			continue
+		}
+		inst := sizeInst(sizes[i])
+		buf := []uint8{235 + inst, 0, 0, 0, 0, 0, 0, 0, 0}
+		putUvarint(buf[1:], c.scratch)
+		bytes = append(bytes, buf...)
+		bytes = append(bytes, c.frameAddr(l.info.pos+offs[i])...)
+		buf = []uint8{230 + inst, 0, 0, 0, 0, 0, 0, 0, 0, 215 + inst}
+		putUvarint(buf[1:9], c.scratch)
+		bytes = append(bytes, buf...)
+	}
+	return bytes
+}
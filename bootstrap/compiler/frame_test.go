@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+const sum = `fun{safe} sum(u64:u64) {
+    let n: u64;
+    if (n 0u64 ==(u64,u64:bool)) {
+        0u64
+    } else {
+        n 1u64 -(u64,u64:u64) sum(u64:u64)
+        n +(u64,u64:u64)
+    }
+}
+`
+
+func u64s(stack []uint8) []uint64 {
+	res := []uint64{}
+	for len(stack) >= 8 {
+		res = append(res, binary.LittleEndian.Uint64(stack))
+		stack = stack[8:]
+	}
+	return res
+}
+
+func expectStack(t *testing.T, name string, stack []uint8, want []uint64) {
+	t.Helper()
+	got := u64s(stack)
+	if len(stack) != 8*len(want) {
+		t.Errorf("%s: stack % x, want %v", name, stack, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: stack %v, want %v", name, got, want)
+			return
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		body  string
+		want  []uint64
+	}{
+		{"recursive", sum, "10u64 sum(u64:u64)", []uint64{55}},
+		{"mutual", sum + `
+fun{safe} twice(u64:u64) {
+    let n: u64;
+    n sum(u64:u64) n sum(u64:u64) +(u64,u64:u64) n +(u64,u64:u64)
+}
+`, "4u64 twice(u64:u64)", []uint64{24}},
+		{"initializer", `
+fun{safe} f(:u64,u64) {
+    let a: u64 2u64;
+    let b: u64 a 3u64 *(u64,u64:u64);
+    a b
+}
+`, "f(:u64,u64)", []uint64{6, 2}},
+	}
+	for _, test := range tests {
+		expectStack(t, test.name, stackOf(t, test.decls, test.body), test.want)
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"let type", "fun{safe} main(:) {\n    let a: u64 1u8;\n}\n", "main.mvm:4:5: error: the let in fun 'main(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: U64       U8"},
+		{"let missing value", "fun{safe} main(:) {\n    let a: u64;\n}\n", "main.mvm:4:5: error: the let in fun 'main(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: U64"},
+		{"unknown let", "fun{safe} main(:) {\n    a drop(u64:)\n}\n", "main.mvm:4:5: error: unknown ident 'a'"},
+	}
+	for _, test := range tests {
+		_, diags := compileSrc(t, prelude+test.src)
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
+
+const sq = `fun{safe, inline} sq(u64:u64) {
+    let x: u64;
+    x x *(u64,u64:u64)
+}
+
+fun{safe, inline} sumsq(u64,u64:u64) {
+    let b: u64;
+    let a: u64;
+    a sq(u64:u64) b sq(u64:u64) +(u64,u64:u64)
+}
+`
+
+func TestInlineLets(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		body  string
+		want  []uint64
+	}{
+		{"twice", sq, "3u64 sq(u64:u64) 4u64 sq(u64:u64)", []uint64{16, 9}},
+		{"nested", sq, "3u64 4u64 sumsq(u64,u64:u64)", []uint64{25}},
+		{"in recursion", sq + `
+fun{safe} sqsum(u64:u64) {
+    let n: u64;
+    if (n 0u64 ==(u64,u64:bool)) {
+        0u64
+    } else {
+        n 1u64 -(u64,u64:u64) sqsum(u64:u64)
+        n sq(u64:u64) +(u64,u64:u64)
+    }
+}
+`, "4u64 sqsum(u64:u64)", []uint64{30}},
+		{"caller lets", sq + `
+fun{safe} f(u64:u64,u64) {
+    let x: u64;
+    x sq(u64:u64) x
+}
+`, "5u64 f(u64:u64,u64)", []uint64{5, 25}},
+	}
+	for _, test := range tests {
+		expectStack(t, test.name, stackOf(t, test.decls, test.body), test.want)
+	}
+	_, diags := compileSrc(t, prelude+"fun{safe, inline} f(:) {\n    let a: u64 \"s\";\n}\n\nfun{safe} main(:) {\n    f(:)\n}\n")
+	expectDiags(t, "inline let type", diags, "main.mvm:4:5: error: the let in fun 'f(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: U64       STRING")
+}
@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"bootstrap/image"
+	"bootstrap/isa"
+	"bootstrap/lexer"
+)
+
+func (r *Result) dataAddr() uint64 {
+	addr := r.Strings.Addr
+	if addr == 0 {
+		addr = uint64(len(r.Bytes))
+	}
+	for _, sym := range r.Symbols {
+		if sym.Kind == LET && sym.Addr < addr {
+			addr = sym.Addr
+		}
+	}
+	return addr
+}
+
+func (r *Result) entry() uint64 {
+	for _, sym := range r.Symbols {
+		if sym.Kind == FUN && sym.Name == ".entry" {
+			return sym.Addr
+		}
+	}
+	return 0
+}
+
+func (r *Result) Image() *image.Image {
+	data := r.dataAddr()
+	strs := r.Strings.Addr
+	if strs == 0 {
+		strs = uint64(len(r.Bytes))
+	}
+	end := strs + uint64(len(r.Strings.Data))
+	syms := []image.Symbol{}
+	for _, sym := range r.Symbols {
+		syms = append(syms, image.Symbol{Kind: string(sym.Kind), Name: sym.Name, Addr: sym.Addr, Size: sym.Size})
+	}
+	img := &image.Image{
+		Version: image.Version,
+		Isa:     isa.Version,
+		Entry:   r.entry(),
+		Sections: []image.Section{
+			{Kind: image.CODE, Addr: 0, Data: r.Bytes[:data]},
+			{Kind: image.DATA, Addr: data, Data: r.Bytes[data:strs]},
+			{Kind: image.STRINGS, Addr: strs, Data: r.Bytes[strs:end]},
+			{Kind: image.SYMBOLS, Data: image.EncodeSymbols(syms)},
+		},
+	}
+	if end < uint64(len(r.Bytes)) {
+		img.Sections = append(img.Sections, image.Section{Kind: image.BACKTRACE, Addr: end, Data: r.Bytes[end:]})
+	}
+	if len(r.Lines) != 0 {
+		lines := []image.Line{}
+		for _, line := range r.Lines {
+			lines = append(lines, image.Line{Addr: line.Addr, Size: line.Size, File: line.Span.File, Line: uint32(line.Span.Start.Line), Col: uint32(line.Span.Start.Col), Fun: line.Fun})
+		}
+		img.Sections = append(img.Sections, image.Section{Kind: image.DEBUG, Data: image.EncodeLines(lines)})
+	}
+	return img
+}
+
+func Load(bytes []uint8) (*Result, error) {
+	if !image.IsImage(bytes) {
+		return &Result{Bytes: bytes}, nil
+	}
+	img, err := image.Decode(bytes)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := img.Memory()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Bytes: mem}
+	if s := img.Section(image.STRINGS); s != nil && len(s.Data) != 0 {
+		res.Strings = Strings{Addr: s.Addr, Data: string(s.Data)}
+	}
+	if s := img.Section(image.SYMBOLS); s != nil {
+		syms, err := image.DecodeSymbols(s.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range syms {
+			res.Symbols = append(res.Symbols, Symbol{Kind: SymbolKind(sym.Kind), Name: sym.Name, Addr: sym.Addr, Size: sym.Size})
+		}
+	}
+	if s := img.Section(image.DEBUG); s != nil {
+		lines, err := image.DecodeLines(s.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			pos := lexer.Pos{Line: int(line.Line), Col: int(line.Col)}
+			res.Lines = append(res.Lines, Line{Addr: line.Addr, Size: line.Size, Span: lexer.Span{File: line.File, Start: pos, End: pos}, Fun: line.Fun})
+		}
+	}
+	return res, nil
+}
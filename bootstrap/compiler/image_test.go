@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"bootstrap/image"
+	"bootstrap/vm"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	res := mustCompile(t, prelude+fib)
+	img := res.Image()
+	entry := symbol(res, ".entry")
+	if entry == nil || img.Entry != entry.Addr || entry.Size == 0 {
+		t.Errorf("entry point 0x%x, symbol %+v", img.Entry, entry)
+	}
+	for _, kind := range []image.SectionKind{image.CODE, image.DATA, image.STRINGS, image.SYMBOLS, image.BACKTRACE} {
+		if img.Section(kind) == nil {
+			t.Errorf("missing %s section", kind)
+		}
+	}
+
+	loaded, err := Load(img.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Bytes, res.Bytes) {
+		t.Errorf("loaded bytes differ")
+	}
+	if loaded.Strings != res.Strings {
+		t.Errorf("strings %+v, want %+v", loaded.Strings, res.Strings)
+	}
+	if len(loaded.Symbols) != len(res.Symbols) || symbol(loaded, "fib(u64:u64)") == nil {
+		t.Errorf("symbols %+v, want %+v", loaded.Symbols, res.Symbols)
+	}
+
+	raw, _ := run(t, res)
+	m, err := vm.Load(img.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	m.Stdin = strings.NewReader("")
+	m.Stdout = out
+	if err := m.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m.Stack(), raw) {
+		t.Errorf("image stack % x, raw stack % x", m.Stack(), raw)
+	}
+}
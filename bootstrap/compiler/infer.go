@@ -0,0 +1,248 @@
+package compiler
+
+import "bootstrap/parser"
+
+type literal struct {
+	number *parser.Number
+}
+
+func (l *literal) String(*parser.Types) string {
+	return "{number}"
+}
+
+func (l *literal) Size(*parser.Types) int {
+	return 0
+}
+
+func (l *literal) Sub(*parser.Types) []parser.Typ {
+	return nil
+}
+
+func (l *literal) LoadSizes(*parser.Types) []int {
+	return nil
+}
+
+func (l *literal) IsNever() bool {
+	return false
+}
+
+func (l *literal) fits(typ parser.Typ) bool {
+	number := *l.number
+	if !number.SetTyp(typ) {
+		return false
+	}
+	_, ok := numberValue(&number)
+	return ok
+}
+
+func numberTyp(number *parser.Number) parser.Typ {
+	if number.Typ == nil {
+		return &literal{number: number}
+	}
+	return number.Typ
+}
+
+type inferer struct {
+	fun  *parser.Fun
+	lets map[string]parser.Typ
+	hole bool
+}
+
+func eachNumber(exprs []parser.Expr, fn func(*parser.Number)) {
+	for _, expr := range exprs {
+		if number := expr.AsNumber(); number != nil {
+			fn(number)
+		} else if ifel := expr.AsIf(); ifel != nil {
+			eachNumber(ifel.Con, fn)
+			eachNumber(ifel.Exprs, fn)
+			eachNumber(ifel.Else, fn)
+		} else if while := expr.AsWhile(); while != nil {
+			eachNumber(while.Con, fn)
+			eachNumber(while.Exprs, fn)
+		}
+	}
+}
+
+func numbers(funs []*parser.Fun, lets []*parser.Let, fn func(*parser.Number)) {
+	for _, let := range lets {
+		eachNumber(let.Exprs, fn)
+	}
+	for _, fun := range funs {
+		for _, let := range fun.Block.Lets {
+			eachNumber(let.Exprs, fn)
+		}
+		eachNumber(fun.Block.Exprs, fn)
+	}
+}
+
+func (c *Ctx) infer(funs []*parser.Fun, lets []*parser.Let) {
+	numbers(funs, lets, func(number *parser.Number) {
+		if number.Typ != nil {
+			c.checkNumber(number)
+		}
+	})
+	for _, let := range lets {
+		c.inferLet(let)
+	}
+	ambiguous := func(number *parser.Number) {
+		if number.Typ == nil {
+			c.errorf(number.Span, "the type of the number '%s' is ambiguous, add a type suffix", numText(number))
+		}
+	}
+	numbers(nil, lets, ambiguous)
+	for _, fun := range funs {
+		if c.inferFun(fun) {
+			numbers([]*parser.Fun{fun}, nil, ambiguous)
+		}
+	}
+}
+
+func (c *Ctx) bind(l *literal, typ parser.Typ) {
+	if _, ok := typ.(*literal); ok {
+		return
+	}
+	if l.number.Typ != nil && l.number.Typ.String(c.types) == typ.String(c.types) {
+		return
+	}
+	if !l.number.SetTyp(typ) {
+		l.number.Typ = typ
+		c.fatalf(l.number.Span, "the number '%s' can't be used as '%s'", numText(l.number), typ.String(c.types))
+	}
+	c.checkNumber(l.number)
+}
+
+func (c *Ctx) inferLet(let *parser.Let) {
+	defer c.catch()
+
+	for _, expr := range let.Exprs {
+		if number := expr.AsNumber(); number != nil && number.Typ == nil {
+			c.bind(&literal{number: number}, let.Typ)
+		}
+	}
+}
+
+func (c *Ctx) inferFun(fun *parser.Fun) (done bool) {
+	defer c.catch()
+
+	for _, opt := range fun.Opts {
+		if opt.Content == "asm" {
+			return true
+		}
+	}
+	in := &inferer{fun: fun, lets: make(map[string]parser.Typ)}
+	for _, let := range fun.Block.Lets {
+		in.lets[let.Ident.Content] = let.Typ
+	}
+	stack := append([]parser.Typ{}, fun.Inputs...)
+	for _, let := range fun.Block.Lets {
+		var end bool
+		stack, end = c.inferExprs(in, stack, let.Exprs)
+		if end {
+			return !in.hole
+		}
+		stack = c.match(stack, let.Typ)
+	}
+	stack, end := c.inferExprs(in, stack, fun.Block.Exprs)
+	if !end {
+		c.match(stack, fun.Outputs...)
+	}
+	return !in.hole
+}
+
+func (c *Ctx) match(stack []parser.Typ, typs ...parser.Typ) []parser.Typ {
+	if len(typs) > len(stack) {
+		panic(bailout{})
+	}
+	rest := len(stack) - len(typs)
+	for i, typ := range typs {
+		c.unify(stack[rest+i], typ)
+	}
+	return stack[:rest]
+}
+
+func (c *Ctx) unify(a parser.Typ, b parser.Typ) {
+	if l, ok := a.(*literal); ok {
+		c.bind(l, b)
+	} else if l, ok := b.(*literal); ok {
+		c.bind(l, a)
+	} else if a.String(c.types) != b.String(c.types) {
+		panic(bailout{})
+	}
+}
+
+func (c *Ctx) unifyStacks(a []parser.Typ, b []parser.Typ) {
+	if len(a) != len(b) {
+		panic(bailout{})
+	}
+	for i := range a {
+		c.unify(a[i], b[i])
+	}
+}
+
+func (c *Ctx) inferExprs(in *inferer, stack []parser.Typ, exprs []parser.Expr) ([]parser.Typ, bool) {
+	for _, expr := range exprs {
+		if ident := expr.AsIdent(); ident != nil {
+			typ := in.lets[ident.Content]
+			if typ == nil {
+				let := c.lets[ident.Content]
+				if let == nil {
+					panic(bailout{})
+				}
+				typ = let.let.Typ
+			}
+			stack = append(stack, typ)
+		} else if call := expr.AsCall(); call != nil {
+			stack = append(c.match(stack, call.Inputs...), call.Outputs...)
+			if containsNever(call.Outputs) {
+				return stack, true
+			}
+		} else if number := expr.AsNumber(); number != nil {
+			stack = append(stack, numberTyp(number))
+		} else if expr.AsString() != nil {
+			stack = append(stack, parser.STRING)
+		} else if ifel := expr.AsIf(); ifel != nil {
+			stack, _ = c.inferExprs(in, stack, ifel.Con)
+			stack = c.match(stack, parser.BOOL)
+			iStack, iEnd := c.inferExprs(in, append([]parser.Typ{}, stack...), ifel.Exprs)
+			eStack, eEnd := c.inferExprs(in, append([]parser.Typ{}, stack...), ifel.Else)
+			if iEnd && eEnd {
+				return stack, true
+			}
+			if iEnd {
+				stack = eStack
+			} else if eEnd {
+				stack = iStack
+			} else {
+				c.unifyStacks(iStack, eStack)
+				stack = iStack
+			}
+		} else if while := expr.AsWhile(); while != nil {
+			stack, _ = c.inferExprs(in, stack, while.Con)
+			stack = c.match(stack, parser.BOOL)
+			wStack, end := c.inferExprs(in, append([]parser.Typ{}, stack...), while.Exprs)
+			if !end {
+				c.unifyStacks(stack, wStack)
+			}
+		} else if expr.AsUnwrap() != nil {
+			if len(stack) == 0 {
+				panic(bailout{})
+			}
+			last := len(stack) - 1
+			if _, ok := stack[last].(*literal); ok {
+				panic(bailout{})
+			}
+			stack = append(stack[:last], stack[last].Sub(c.types)...)
+		} else if wrap := expr.AsWrap(); wrap != nil {
+			stack = append(c.match(stack, wrap.Typ.Sub(c.types)...), wrap.Typ)
+		} else if expr.AsAddr() != nil {
+			stack = append(stack, parser.U64)
+		} else if expr.AsReturn() != nil {
+			c.match(stack, in.fun.Outputs...)
+			return stack, true
+		} else if expr.AsHole() != nil {
+			in.hole = true
+			return stack, true
+		}
+	}
+	return stack, false
+}
@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name  string
+		decls string
+		body  string
+		want  []uint8
+	}{
+		{"call inputs", "", "5 2u8 +(u8,u8:u8)", le("07")},
+		{"signed", "", "-3 to(i64:u64)", le("fffffffffffffffd")},
+		{"fun outputs", "fun{safe} seven(:u32) {\n    7\n}\n", "seven(:u32)", le("00000007")},
+		{"static let", "let k: u16 300;\n", "k", le("012c")},
+		{"local let", "fun{safe} k(:u16) {\n    let a: u16 1;\n    a\n}\n", "k(:u16)", le("0001")},
+		{"comparison", "", "3 4u16 <(u16,u16:bool) drop(bool:) 9 to(u8:u64)", le("0000000000000009")},
+		{"if branches", "", "true(:bool) if () { 1 } else { 2u16 }", le("0001")},
+		{"while", "", "0 while (.(u32:u32,u32) 3 <(u32,u32:bool)) { ++(u32:u32) }", le("00000003")},
+	}
+	for _, test := range tests {
+		if got := stackOf(t, test.decls, test.body); !bytes.Equal(got, test.want) {
+			t.Errorf("%s: stack % x, want % x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"ambiguous",
+			"fun{safe} main(:) {\n    1\n}\n",
+			"main.mvm:4:5: error: the type of the number '1' is ambiguous, add a type suffix",
+		},
+		{
+			"out of range",
+			"fun{safe} main(:) {\n    300 drop(u8:)\n}\n",
+			"main.mvm:4:5: error: the number '300' is out of range for 'U8'",
+		},
+		{
+			"not a number",
+			"fun{safe} main(:) {\n    1 drop(string:)\n}\n",
+			"main.mvm:4:5: error: the number '1' can't be used as 'STRING'",
+		},
+		{
+			"stack error first",
+			"fun{safe} main(:) {\n    1i128 .unwrap drop(i128:) -3 to(i64:u64) drop(u64:)\n}\n",
+			"main.mvm:4:19: error: the fun 'main(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: I128      U128",
+		},
+		{
+			"stack error after literal",
+			"fun{safe} main(:) {\n    2 true(:bool) drop(u8:)\n}\n",
+			"main.mvm:4:19: error: the fun 'main(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: U8        BOOL\n\tnote:           {number}",
+		},
+		{
+			"empty hex",
+			"fun{safe} main(:) {\n    0x drop(u8:)\n}\n",
+			"main.mvm:4:5: error: invalid number literal '0x'",
+		},
+		{
+			"bad suffix",
+			"fun{safe} main(:) {\n    1q64 drop(u64:)\n}\n",
+			"main.mvm:4:5: error: invalid number literal '1q64'",
+		},
+		{
+			"bad binary digit",
+			"fun{safe} main(:) {\n    0b102u8 drop(u8:)\n}\n",
+			"main.mvm:4:5: error: invalid number literal '0b102u8'",
+		},
+	}
+	for _, test := range tests {
+		_, diags := compileSrc(t, prelude+test.src)
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
@@ -0,0 +1,75 @@
+package compiler
+
+import (
+	"bootstrap/isa"
+	"bootstrap/parser"
+	"fmt"
+	"strings"
+)
+
+type instSet struct {
+	insts    map[string]uint8
+	immInsts map[string]imm
+	ext      map[string]isa.Inst
+}
+
+var builtinInsts = &instSet{insts: insts, immInsts: immInsts}
+
+func newInstSet(ext []isa.Inst) (*instSet, error) {
+	if len(ext) == 0 {
+		return builtinInsts, nil
+	}
+	s := &instSet{insts: make(map[string]uint8), immInsts: make(map[string]imm), ext: make(map[string]isa.Inst)}
+	used := make(map[uint8]string)
+	for name, code := range insts {
+		s.insts[name] = code
+		used[code] = name
+	}
+	for name, imm := range immInsts {
+		s.immInsts[name] = imm
+		used[imm.code] = name
+	}
+	for _, inst := range ext {
+		if _, ok := s.lookup(inst.Name); ok {
+			return nil, fmt.Errorf("the instruction '%s' already exists", inst.Name)
+		}
+		if name, ok := used[inst.Code]; ok {
+			return nil, fmt.Errorf("the opcode %03d of '%s' is already used by '%s'", inst.Code, inst.Name, name)
+		}
+		if inst.Imm == 0 {
+			s.insts[inst.Name] = inst.Code
+		} else {
+			s.immInsts[inst.Name] = imm{code: inst.Code, size: inst.Imm}
+		}
+		s.ext[inst.Name] = inst
+		used[inst.Code] = inst.Name
+	}
+	return s, nil
+}
+
+func (s *instSet) lookup(op string) (imm, bool) {
+	if code, ok := s.insts[op]; ok {
+		return imm{code: code}, true
+	}
+	imm, ok := s.immInsts[op]
+	return imm, ok
+}
+
+func extTyps(typs []string) []parser.Typ {
+	res := []parser.Builtin{}
+	for _, typ := range typs {
+		if typ == "!" {
+			res = append(res, parser.NEVER)
+		} else {
+			res = append(res, parser.Builtin(strings.ToUpper(typ)))
+		}
+	}
+	return args(res...)
+}
+
+func (s *instSet) args(op string) ([]parser.Typ, []parser.Typ, bool) {
+	if inst, ok := s.ext[op]; ok {
+		return extTyps(inst.Inputs), extTyps(inst.Outputs), true
+	}
+	return argsInst(op)
+}
@@ -0,0 +1,140 @@
+package compiler
+
+import (
+	"bootstrap/diag"
+	"bootstrap/isa"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+const ext = `
+# extra opcodes of a custom vm
+247 square_u64 (u64:u64)
+248 add_imm_u8 (u8:u8) 1
+`
+
+func extInsts(t *testing.T) []isa.Inst {
+	t.Helper()
+	insts, err := isa.Parse(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return insts
+}
+
+func compileExt(t *testing.T, src string, insts []isa.Inst) (*Result, []diag.Diagnostic) {
+	t.Helper()
+	fsys := files(map[string]string{"main.mvm": prelude + src})
+	return Compile(context.Background(), Options{Entry: "main.mvm", FS: fsys, Builtin: os.DirFS(".."), Insts: insts})
+}
+
+const extFuns = `fun{unsafe, inline, asm} square(u64:u64) {
+    "square_u64"
+}
+
+fun{unsafe, inline, asm} add5(u8:u8) {
+    "add_imm_u8 5"
+}
+`
+
+func TestInstSet(t *testing.T) {
+	src := extFuns + "\nfun{unsafe} main(:) {\n    3u64 square(u64:u64) drop(u64:) 1u8 add5(u8:u8) drop(u8:)\n}\n"
+	res, diags := compileExt(t, src, extInsts(t))
+	if res == nil || diag.HasErrors(diags) {
+		t.Fatalf("compile failed:\n%s", messages(diags))
+	}
+	sym := symbol(res, "main(:)")
+	if sym == nil {
+		t.Fatal("missing main(:)")
+	}
+	code := res.Bytes[sym.Addr : sym.Addr+sym.Size]
+	for _, want := range [][]uint8{{247}, {248, 5}} {
+		if !bytes.Contains(code, want) {
+			t.Errorf("main(:) doesn't contain % x", want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, res.Bytes, res.Symbols, res.Strings, extInsts(t)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"    square_u64 ", "    add_imm_u8 0x5 "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("'%s' is missing from the disassembly", want)
+		}
+	}
+	asm, diags := Assemble("main.asm", out.String(), extInsts(t))
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics:\n%s", messages(diags))
+	}
+	if !bytes.Equal(asm.Bytes, res.Bytes) {
+		t.Errorf("reassembled bytes differ")
+	}
+}
+
+func TestInstSetErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		insts string
+		want  string
+	}{
+		{
+			"not loaded",
+			extFuns + "\nfun{unsafe} main(:) {\n    3u64 square(u64:u64) drop(u64:)\n}\n",
+			"",
+			"main.mvm:4:5: error: invalid asm instruction 'square_u64'",
+		},
+		{
+			"stack effect",
+			extFuns + "\nfun{unsafe} main(:) {\n    3u8 square(u64:u64) drop(u64:)\n}\n",
+			ext,
+			"main.mvm:12:9: error: the fun 'main(:)' does not have a valid stack\n\tnote: expected  actual\n\tnote: U64       U8",
+		},
+		{
+			"opcode in use",
+			"",
+			"001 stop (:)",
+			"error: the opcode 001 of 'stop' is already used by 'halt'",
+		},
+		{
+			"name in use",
+			"",
+			"247 halt (:)",
+			"error: the instruction 'halt' already exists",
+		},
+	}
+	for _, test := range tests {
+		insts, err := isa.Parse(test.insts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, diags := compileExt(t, test.src+"\nfun{unsafe} other(:) {\n}\n", insts)
+		expectDiags(t, test.name, diags, test.want)
+	}
+}
+
+func TestParseInsts(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"fields", "247 square_u64", "line 1: expected 'code name (inputs:outputs) [imm]'"},
+		{"opcode", "300 big (:)", "line 1: invalid opcode '300'"},
+		{"effect", "247 square_u64 (u64)", "line 1: invalid stack effect '(u64)'"},
+		{"type", "247 square_f64 (f64:f64)", "line 1: unknown type 'f64'"},
+		{"immediate", "247 push_imm_u24 (:u64) 3", "line 1: invalid immediate size '3'"},
+		{"opcode twice", "247 a (:)\n247 b (:)", "line 2: the opcode 247 is already used by 'a'"},
+		{"name twice", "247 a (:)\n248 a (:)", "line 2: the instruction 'a' already exists"},
+	}
+	for _, test := range tests {
+		_, err := isa.Parse(test.src)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want '%s'", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,1006 @@
+// Code generated by isa/gen from isa/isa.txt; DO NOT EDIT.
+
+package compiler
+
+import "bootstrap/parser"
+
+var insts = map[string]uint8{
+	"nop":           0,
+	"halt":          1,
+	"call":          2,
+	"return":        3,
+	"inter":         4,
+	"alloc":         5,
+	"read":          6,
+	"write":         7,
+	"read_file":     8,
+	"write_file":    9,
+	"pop_sp":        15,
+	"pop_cs":        16,
+	"pop_ih":        17,
+	"pop_ir":        18,
+	"push_ir":       19,
+	"drop_u8":       20,
+	"drop_u16":      21,
+	"drop_u32":      22,
+	"drop_u64":      23,
+	"drop_u128":     24,
+	"negate_i8":     25,
+	"negate_i16":    26,
+	"negate_i32":    27,
+	"negate_i64":    28,
+	"negate_i128":   29,
+	"swap_u8":       30,
+	"swap_u16":      31,
+	"swap_u32":      32,
+	"swap_u64":      33,
+	"swap_u128":     34,
+	"rotate_u8":     35,
+	"rotate_u16":    36,
+	"rotate_u32":    37,
+	"rotate_u64":    38,
+	"rotate_u128":   39,
+	"dup_u8":        40,
+	"dup_u16":       41,
+	"dup_u32":       42,
+	"dup_u64":       43,
+	"dup_u128":      44,
+	"over_u8":       45,
+	"over_u16":      46,
+	"over_u32":      47,
+	"over_u64":      48,
+	"over_u128":     49,
+	"and_u8":        50,
+	"and_u16":       51,
+	"and_u32":       52,
+	"and_u64":       53,
+	"and_u128":      54,
+	"or_u8":         55,
+	"or_u16":        56,
+	"or_u32":        57,
+	"or_u64":        58,
+	"or_u128":       59,
+	"shift_l_u8":    60,
+	"shift_l_u16":   61,
+	"shift_l_u32":   62,
+	"shift_l_u64":   63,
+	"shift_l_u128":  64,
+	"shift_r_u8":    65,
+	"shift_r_u16":   66,
+	"shift_r_u32":   67,
+	"shift_r_u64":   68,
+	"shift_r_u128":  69,
+	"rotate_l_u8":   70,
+	"rotate_l_u16":  71,
+	"rotate_l_u32":  72,
+	"rotate_l_u64":  73,
+	"rotate_l_u128": 74,
+	"rotate_r_u8":   75,
+	"rotate_r_u16":  76,
+	"rotate_r_u32":  77,
+	"rotate_r_u64":  78,
+	"rotate_r_u128": 79,
+	"eq_u8":         80,
+	"eq_u16":        81,
+	"eq_u32":        82,
+	"eq_u64":        83,
+	"eq_u128":       84,
+	"not_eq_u8":     85,
+	"not_eq_u16":    86,
+	"not_eq_u32":    87,
+	"not_eq_u64":    88,
+	"not_eq_u128":   89,
+	"jump":          90,
+	"jump_f":        91,
+	"jump_b":        92,
+	"sleep":         94,
+	"branch":        95,
+	"branch_f":      96,
+	"branch_b":      97,
+	"push_cs":       98,
+	"add_u8":        100,
+	"add_u16":       101,
+	"add_u32":       102,
+	"add_u64":       103,
+	"add_u128":      104,
+	"add_i8":        105,
+	"add_i16":       106,
+	"add_i32":       107,
+	"add_i64":       108,
+	"add_i128":      109,
+	"sub_u8":        110,
+	"sub_u16":       111,
+	"sub_u32":       112,
+	"sub_u64":       113,
+	"sub_u128":      114,
+	"sub_i8":        115,
+	"sub_i16":       116,
+	"sub_i32":       117,
+	"sub_i64":       118,
+	"sub_i128":      119,
+	"mul_u8":        120,
+	"mul_u16":       121,
+	"mul_u32":       122,
+	"mul_u64":       123,
+	"mul_u128":      124,
+	"mul_i8":        125,
+	"mul_i16":       126,
+	"mul_i32":       127,
+	"mul_i64":       128,
+	"mul_i128":      129,
+	"div_u8":        130,
+	"div_u16":       131,
+	"div_u32":       132,
+	"div_u64":       133,
+	"div_u128":      134,
+	"div_i8":        135,
+	"div_i16":       136,
+	"div_i32":       137,
+	"div_i64":       138,
+	"div_i128":      139,
+	"mod_u8":        140,
+	"mod_u16":       141,
+	"mod_u32":       142,
+	"mod_u64":       143,
+	"mod_u128":      144,
+	"mod_i8":        145,
+	"mod_i16":       146,
+	"mod_i32":       147,
+	"mod_i64":       148,
+	"mod_i128":      149,
+	"less_u8":       150,
+	"less_u16":      151,
+	"less_u32":      152,
+	"less_u64":      153,
+	"less_u128":     154,
+	"less_i8":       155,
+	"less_i16":      156,
+	"less_i32":      157,
+	"less_i64":      158,
+	"less_i128":     159,
+	"less_eq_u8":    160,
+	"less_eq_u16":   161,
+	"less_eq_u32":   162,
+	"less_eq_u64":   163,
+	"less_eq_u128":  164,
+	"less_eq_i8":    165,
+	"less_eq_i16":   166,
+	"less_eq_i32":   167,
+	"less_eq_i64":   168,
+	"less_eq_i128":  169,
+	"great_u8":      170,
+	"great_u16":     171,
+	"great_u32":     172,
+	"great_u64":     173,
+	"great_u128":    174,
+	"great_i8":      175,
+	"great_i16":     176,
+	"great_i32":     177,
+	"great_i64":     178,
+	"great_i128":    179,
+	"great_eq_u8":   180,
+	"great_eq_u16":  181,
+	"great_eq_u32":  182,
+	"great_eq_u64":  183,
+	"great_eq_u128": 184,
+	"great_eq_i8":   185,
+	"great_eq_i16":  186,
+	"great_eq_i32":  187,
+	"great_eq_i64":  188,
+	"great_eq_i128": 189,
+	"u8_to_u16":     190,
+	"u8_to_u32":     191,
+	"u8_to_u64":     192,
+	"u8_to_u128":    193,
+	"u16_to_u8":     194,
+	"u16_to_u32":    195,
+	"u16_to_u64":    196,
+	"u16_to_u128":   197,
+	"u32_to_u8":     198,
+	"u32_to_u16":    199,
+	"u32_to_u64":    200,
+	"u32_to_u128":   201,
+	"u64_to_u8":     202,
+	"u64_to_u16":    203,
+	"u64_to_u32":    204,
+	"u64_to_u128":   205,
+	"u128_to_u8":    206,
+	"u128_to_u16":   207,
+	"u128_to_u32":   208,
+	"u128_to_u64":   209,
+	"load_u8":       210,
+	"load_u16":      211,
+	"load_u32":      212,
+	"load_u64":      213,
+	"load_u128":     214,
+	"store_u8":      215,
+	"store_u16":     216,
+	"store_u32":     217,
+	"store_u64":     218,
+	"store_u128":    219,
+	"xor_u8":        240,
+	"xor_u16":       241,
+	"xor_u32":       242,
+	"xor_u64":       243,
+	"xor_u128":      244,
+	"debug":         250,
+	"debug_u8":      251,
+	"debug_u16":     252,
+	"debug_u32":     253,
+	"debug_u64":     254,
+	"debug_u128":    255,
+}
+
+var immInsts = map[string]imm{
+	"push_imm_u8":    {10, 1},
+	"push_imm_u16":   {11, 2},
+	"push_imm_u32":   {12, 4},
+	"push_imm_u64":   {13, 8},
+	"push_imm_u128":  {14, 16},
+	"jump_imm":       {220, 8},
+	"jump_imm_f":     {221, 8},
+	"jump_imm_b":     {222, 8},
+	"sleep_imm":      {224, 8},
+	"branch_imm":     {225, 8},
+	"branch_imm_f":   {226, 8},
+	"branch_imm_b":   {227, 8},
+	"call_imm":       {229, 8},
+	"load_imm_u8":    {230, 8},
+	"load_imm_u16":   {231, 8},
+	"load_imm_u32":   {232, 8},
+	"load_imm_u64":   {233, 8},
+	"load_imm_u128":  {234, 8},
+	"store_imm_u8":   {235, 8},
+	"store_imm_u16":  {236, 8},
+	"store_imm_u32":  {237, 8},
+	"store_imm_u64":  {238, 8},
+	"store_imm_u128": {239, 8},
+}
+
+func argsInst(inst string) ([]parser.Typ, []parser.Typ, bool) {
+	switch inst {
+	// 000
+	case "nop":
+		return args(), args(), true
+	// 001
+	case "halt":
+		return args(), args(parser.NEVER), true
+	// 002
+	case "call":
+		return args(parser.U64), args(), true
+	// 003
+	case "return":
+		return args(), args(), true
+	// 004
+	case "inter":
+		return args(), args(parser.NEVER), true
+	// 005
+	case "alloc":
+		return args(parser.U64), args(parser.U64), true
+	// 006
+	case "read":
+		return args(parser.STRING), args(parser.U64), true
+	// 007
+	case "write":
+		return args(parser.STRING), args(parser.U64), true
+	// 008
+	case "read_file":
+		return args(parser.STRING, parser.STRING), args(parser.U64), true
+	// 009
+	case "write_file":
+		return args(parser.STRING, parser.STRING), args(parser.U64), true
+	// 010
+	case "push_imm_u8":
+		return args(), args(parser.U8), true
+	// 011
+	case "push_imm_u16":
+		return args(), args(parser.U16), true
+	// 012
+	case "push_imm_u32":
+		return args(), args(parser.U32), true
+	// 013
+	case "push_imm_u64":
+		return args(), args(parser.U64), true
+	// 014
+	case "push_imm_u128":
+		return args(), args(parser.U128), true
+	// 015
+	case "pop_sp":
+		return args(parser.U64), args(), true
+	// 016
+	case "pop_cs":
+		return args(parser.U64), args(), true
+	// 017
+	case "pop_ih":
+		return args(parser.U64), args(), true
+	// 018
+	case "pop_ir":
+		return args(parser.I8), args(), true
+	// 019
+	case "push_ir":
+		return args(), args(parser.I8), true
+	// 020
+	case "drop_u8":
+		return args(parser.U8), args(), true
+	// 021
+	case "drop_u16":
+		return args(parser.U16), args(), true
+	// 022
+	case "drop_u32":
+		return args(parser.U32), args(), true
+	// 023
+	case "drop_u64":
+		return args(parser.U64), args(), true
+	// 024
+	case "drop_u128":
+		return args(parser.U128), args(), true
+	// 025
+	case "negate_i8":
+		return args(parser.I8), args(parser.I8), true
+	// 026
+	case "negate_i16":
+		return args(parser.I16), args(parser.I16), true
+	// 027
+	case "negate_i32":
+		return args(parser.I32), args(parser.I32), true
+	// 028
+	case "negate_i64":
+		return args(parser.I64), args(parser.I64), true
+	// 029
+	case "negate_i128":
+		return args(parser.I128), args(parser.I128), true
+	// 030
+	case "swap_u8":
+		return args(parser.U8, parser.U8), args(parser.U8, parser.U8), true
+	// 031
+	case "swap_u16":
+		return args(parser.U16, parser.U16), args(parser.U16, parser.U16), true
+	// 032
+	case "swap_u32":
+		return args(parser.U32, parser.U32), args(parser.U32, parser.U32), true
+	// 033
+	case "swap_u64":
+		return args(parser.U64, parser.U64), args(parser.U64, parser.U64), true
+	// 034
+	case "swap_u128":
+		return args(parser.U128, parser.U128), args(parser.U128, parser.U128), true
+	// 035
+	case "rotate_u8":
+		return args(parser.U8, parser.U8, parser.U8), args(parser.U8, parser.U8, parser.U8), true
+	// 036
+	case "rotate_u16":
+		return args(parser.U16, parser.U16, parser.U16), args(parser.U16, parser.U16, parser.U16), true
+	// 037
+	case "rotate_u32":
+		return args(parser.U32, parser.U32, parser.U32), args(parser.U32, parser.U32, parser.U32), true
+	// 038
+	case "rotate_u64":
+		return args(parser.U64, parser.U64, parser.U64), args(parser.U64, parser.U64, parser.U64), true
+	// 039
+	case "rotate_u128":
+		return args(parser.U128, parser.U128, parser.U128), args(parser.U128, parser.U128, parser.U128), true
+	// 040
+	case "dup_u8":
+		return args(parser.U8), args(parser.U8, parser.U8), true
+	// 041
+	case "dup_u16":
+		return args(parser.U16), args(parser.U16, parser.U16), true
+	// 042
+	case "dup_u32":
+		return args(parser.U32), args(parser.U32, parser.U32), true
+	// 043
+	case "dup_u64":
+		return args(parser.U64), args(parser.U64, parser.U64), true
+	// 044
+	case "dup_u128":
+		return args(parser.U128), args(parser.U128, parser.U128), true
+	// 045
+	case "over_u8":
+		return args(parser.U8, parser.U8), args(parser.U8, parser.U8, parser.U8), true
+	// 046
+	case "over_u16":
+		return args(parser.U16, parser.U16), args(parser.U16, parser.U16, parser.U16), true
+	// 047
+	case "over_u32":
+		return args(parser.U32, parser.U32), args(parser.U32, parser.U32, parser.U32), true
+	// 048
+	case "over_u64":
+		return args(parser.U64, parser.U64), args(parser.U64, parser.U64, parser.U64), true
+	// 049
+	case "over_u128":
+		return args(parser.U128, parser.U128), args(parser.U128, parser.U128, parser.U128), true
+	// 050
+	case "and_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 051
+	case "and_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 052
+	case "and_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 053
+	case "and_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 054
+	case "and_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 055
+	case "or_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 056
+	case "or_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 057
+	case "or_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 058
+	case "or_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 059
+	case "or_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 060
+	case "shift_l_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 061
+	case "shift_l_u16":
+		return args(parser.U16, parser.U8), args(parser.U16), true
+	// 062
+	case "shift_l_u32":
+		return args(parser.U32, parser.U8), args(parser.U32), true
+	// 063
+	case "shift_l_u64":
+		return args(parser.U64, parser.U8), args(parser.U64), true
+	// 064
+	case "shift_l_u128":
+		return args(parser.U128, parser.U8), args(parser.U128), true
+	// 065
+	case "shift_r_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 066
+	case "shift_r_u16":
+		return args(parser.U16, parser.U8), args(parser.U16), true
+	// 067
+	case "shift_r_u32":
+		return args(parser.U32, parser.U8), args(parser.U32), true
+	// 068
+	case "shift_r_u64":
+		return args(parser.U64, parser.U8), args(parser.U64), true
+	// 069
+	case "shift_r_u128":
+		return args(parser.U128, parser.U8), args(parser.U128), true
+	// 070
+	case "rotate_l_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 071
+	case "rotate_l_u16":
+		return args(parser.U16, parser.U8), args(parser.U16), true
+	// 072
+	case "rotate_l_u32":
+		return args(parser.U32, parser.U8), args(parser.U32), true
+	// 073
+	case "rotate_l_u64":
+		return args(parser.U64, parser.U8), args(parser.U64), true
+	// 074
+	case "rotate_l_u128":
+		return args(parser.U128, parser.U8), args(parser.U128), true
+	// 075
+	case "rotate_r_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 076
+	case "rotate_r_u16":
+		return args(parser.U16, parser.U8), args(parser.U16), true
+	// 077
+	case "rotate_r_u32":
+		return args(parser.U32, parser.U8), args(parser.U32), true
+	// 078
+	case "rotate_r_u64":
+		return args(parser.U64, parser.U8), args(parser.U64), true
+	// 079
+	case "rotate_r_u128":
+		return args(parser.U128, parser.U8), args(parser.U128), true
+	// 080
+	case "eq_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 081
+	case "eq_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 082
+	case "eq_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 083
+	case "eq_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 084
+	case "eq_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 085
+	case "not_eq_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 086
+	case "not_eq_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 087
+	case "not_eq_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 088
+	case "not_eq_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 089
+	case "not_eq_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 090
+	case "jump":
+		return args(parser.U64), args(), true
+	// 091
+	case "jump_f":
+		return args(parser.U64), args(), true
+	// 092
+	case "jump_b":
+		return args(parser.U64), args(), true
+	// 094
+	case "sleep":
+		return args(parser.U64), args(), true
+	// 095
+	case "branch":
+		return args(parser.U64, parser.BOOL), args(), true
+	// 096
+	case "branch_f":
+		return args(parser.U64, parser.BOOL), args(), true
+	// 097
+	case "branch_b":
+		return args(parser.U64, parser.BOOL), args(), true
+	// 098
+	case "push_cs":
+		return args(), args(parser.U64), true
+	// 100
+	case "add_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 101
+	case "add_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 102
+	case "add_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 103
+	case "add_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 104
+	case "add_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 105
+	case "add_i8":
+		return args(parser.I8, parser.I8), args(parser.I8), true
+	// 106
+	case "add_i16":
+		return args(parser.I16, parser.I16), args(parser.I16), true
+	// 107
+	case "add_i32":
+		return args(parser.I32, parser.I32), args(parser.I32), true
+	// 108
+	case "add_i64":
+		return args(parser.I64, parser.I64), args(parser.I64), true
+	// 109
+	case "add_i128":
+		return args(parser.I128, parser.I128), args(parser.I128), true
+	// 110
+	case "sub_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 111
+	case "sub_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 112
+	case "sub_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 113
+	case "sub_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 114
+	case "sub_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 115
+	case "sub_i8":
+		return args(parser.I8, parser.I8), args(parser.I8), true
+	// 116
+	case "sub_i16":
+		return args(parser.I16, parser.I16), args(parser.I16), true
+	// 117
+	case "sub_i32":
+		return args(parser.I32, parser.I32), args(parser.I32), true
+	// 118
+	case "sub_i64":
+		return args(parser.I64, parser.I64), args(parser.I64), true
+	// 119
+	case "sub_i128":
+		return args(parser.I128, parser.I128), args(parser.I128), true
+	// 120
+	case "mul_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 121
+	case "mul_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 122
+	case "mul_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 123
+	case "mul_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 124
+	case "mul_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 125
+	case "mul_i8":
+		return args(parser.I8, parser.I8), args(parser.I8), true
+	// 126
+	case "mul_i16":
+		return args(parser.I16, parser.I16), args(parser.I16), true
+	// 127
+	case "mul_i32":
+		return args(parser.I32, parser.I32), args(parser.I32), true
+	// 128
+	case "mul_i64":
+		return args(parser.I64, parser.I64), args(parser.I64), true
+	// 129
+	case "mul_i128":
+		return args(parser.I128, parser.I128), args(parser.I128), true
+	// 130
+	case "div_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 131
+	case "div_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 132
+	case "div_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 133
+	case "div_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 134
+	case "div_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 135
+	case "div_i8":
+		return args(parser.I8, parser.I8), args(parser.I8), true
+	// 136
+	case "div_i16":
+		return args(parser.I16, parser.I16), args(parser.I16), true
+	// 137
+	case "div_i32":
+		return args(parser.I32, parser.I32), args(parser.I32), true
+	// 138
+	case "div_i64":
+		return args(parser.I64, parser.I64), args(parser.I64), true
+	// 139
+	case "div_i128":
+		return args(parser.I128, parser.I128), args(parser.I128), true
+	// 140
+	case "mod_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 141
+	case "mod_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 142
+	case "mod_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 143
+	case "mod_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 144
+	case "mod_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 145
+	case "mod_i8":
+		return args(parser.I8, parser.I8), args(parser.I8), true
+	// 146
+	case "mod_i16":
+		return args(parser.I16, parser.I16), args(parser.I16), true
+	// 147
+	case "mod_i32":
+		return args(parser.I32, parser.I32), args(parser.I32), true
+	// 148
+	case "mod_i64":
+		return args(parser.I64, parser.I64), args(parser.I64), true
+	// 149
+	case "mod_i128":
+		return args(parser.I128, parser.I128), args(parser.I128), true
+	// 150
+	case "less_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 151
+	case "less_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 152
+	case "less_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 153
+	case "less_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 154
+	case "less_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 155
+	case "less_i8":
+		return args(parser.I8, parser.I8), args(parser.BOOL), true
+	// 156
+	case "less_i16":
+		return args(parser.I16, parser.I16), args(parser.BOOL), true
+	// 157
+	case "less_i32":
+		return args(parser.I32, parser.I32), args(parser.BOOL), true
+	// 158
+	case "less_i64":
+		return args(parser.I64, parser.I64), args(parser.BOOL), true
+	// 159
+	case "less_i128":
+		return args(parser.I128, parser.I128), args(parser.BOOL), true
+	// 160
+	case "less_eq_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 161
+	case "less_eq_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 162
+	case "less_eq_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 163
+	case "less_eq_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 164
+	case "less_eq_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 165
+	case "less_eq_i8":
+		return args(parser.I8, parser.I8), args(parser.BOOL), true
+	// 166
+	case "less_eq_i16":
+		return args(parser.I16, parser.I16), args(parser.BOOL), true
+	// 167
+	case "less_eq_i32":
+		return args(parser.I32, parser.I32), args(parser.BOOL), true
+	// 168
+	case "less_eq_i64":
+		return args(parser.I64, parser.I64), args(parser.BOOL), true
+	// 169
+	case "less_eq_i128":
+		return args(parser.I128, parser.I128), args(parser.BOOL), true
+	// 170
+	case "great_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 171
+	case "great_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 172
+	case "great_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 173
+	case "great_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 174
+	case "great_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 175
+	case "great_i8":
+		return args(parser.I8, parser.I8), args(parser.BOOL), true
+	// 176
+	case "great_i16":
+		return args(parser.I16, parser.I16), args(parser.BOOL), true
+	// 177
+	case "great_i32":
+		return args(parser.I32, parser.I32), args(parser.BOOL), true
+	// 178
+	case "great_i64":
+		return args(parser.I64, parser.I64), args(parser.BOOL), true
+	// 179
+	case "great_i128":
+		return args(parser.I128, parser.I128), args(parser.BOOL), true
+	// 180
+	case "great_eq_u8":
+		return args(parser.U8, parser.U8), args(parser.BOOL), true
+	// 181
+	case "great_eq_u16":
+		return args(parser.U16, parser.U16), args(parser.BOOL), true
+	// 182
+	case "great_eq_u32":
+		return args(parser.U32, parser.U32), args(parser.BOOL), true
+	// 183
+	case "great_eq_u64":
+		return args(parser.U64, parser.U64), args(parser.BOOL), true
+	// 184
+	case "great_eq_u128":
+		return args(parser.U128, parser.U128), args(parser.BOOL), true
+	// 185
+	case "great_eq_i8":
+		return args(parser.I8, parser.I8), args(parser.BOOL), true
+	// 186
+	case "great_eq_i16":
+		return args(parser.I16, parser.I16), args(parser.BOOL), true
+	// 187
+	case "great_eq_i32":
+		return args(parser.I32, parser.I32), args(parser.BOOL), true
+	// 188
+	case "great_eq_i64":
+		return args(parser.I64, parser.I64), args(parser.BOOL), true
+	// 189
+	case "great_eq_i128":
+		return args(parser.I128, parser.I128), args(parser.BOOL), true
+	// 190
+	case "u8_to_u16":
+		return args(parser.U8), args(parser.U16), true
+	// 191
+	case "u8_to_u32":
+		return args(parser.U8), args(parser.U32), true
+	// 192
+	case "u8_to_u64":
+		return args(parser.U8), args(parser.U64), true
+	// 193
+	case "u8_to_u128":
+		return args(parser.U8), args(parser.U128), true
+	// 194
+	case "u16_to_u8":
+		return args(parser.U16), args(parser.U8), true
+	// 195
+	case "u16_to_u32":
+		return args(parser.U16), args(parser.U32), true
+	// 196
+	case "u16_to_u64":
+		return args(parser.U16), args(parser.U64), true
+	// 197
+	case "u16_to_u128":
+		return args(parser.U16), args(parser.U128), true
+	// 198
+	case "u32_to_u8":
+		return args(parser.U32), args(parser.U8), true
+	// 199
+	case "u32_to_u16":
+		return args(parser.U32), args(parser.U16), true
+	// 200
+	case "u32_to_u64":
+		return args(parser.U32), args(parser.U64), true
+	// 201
+	case "u32_to_u128":
+		return args(parser.U32), args(parser.U128), true
+	// 202
+	case "u64_to_u8":
+		return args(parser.U64), args(parser.U8), true
+	// 203
+	case "u64_to_u16":
+		return args(parser.U64), args(parser.U16), true
+	// 204
+	case "u64_to_u32":
+		return args(parser.U64), args(parser.U32), true
+	// 205
+	case "u64_to_u128":
+		return args(parser.U64), args(parser.U128), true
+	// 206
+	case "u128_to_u8":
+		return args(parser.U128), args(parser.U8), true
+	// 207
+	case "u128_to_u16":
+		return args(parser.U128), args(parser.U16), true
+	// 208
+	case "u128_to_u32":
+		return args(parser.U128), args(parser.U32), true
+	// 209
+	case "u128_to_u64":
+		return args(parser.U128), args(parser.U64), true
+	// 210
+	case "load_u8":
+		return args(parser.U64), args(parser.U8), true
+	// 211
+	case "load_u16":
+		return args(parser.U64), args(parser.U16), true
+	// 212
+	case "load_u32":
+		return args(parser.U64), args(parser.U32), true
+	// 213
+	case "load_u64":
+		return args(parser.U64), args(parser.U64), true
+	// 214
+	case "load_u128":
+		return args(parser.U64), args(parser.U128), true
+	// 215
+	case "store_u8":
+		return args(parser.U64, parser.U8), args(), true
+	// 216
+	case "store_u16":
+		return args(parser.U64, parser.U16), args(), true
+	// 217
+	case "store_u32":
+		return args(parser.U64, parser.U32), args(), true
+	// 218
+	case "store_u64":
+		return args(parser.U64, parser.U64), args(), true
+	// 219
+	case "store_u128":
+		return args(parser.U64, parser.U128), args(), true
+	// 220
+	case "jump_imm":
+		return args(), args(), true
+	// 221
+	case "jump_imm_f":
+		return args(), args(), true
+	// 222
+	case "jump_imm_b":
+		return args(), args(), true
+	// 224
+	case "sleep_imm":
+		return args(), args(), true
+	// 225
+	case "branch_imm":
+		return args(parser.BOOL), args(), true
+	// 226
+	case "branch_imm_f":
+		return args(parser.BOOL), args(), true
+	// 227
+	case "branch_imm_b":
+		return args(parser.BOOL), args(), true
+	// 229
+	case "call_imm":
+		return args(), args(), true
+	// 230
+	case "load_imm_u8":
+		return args(), args(parser.U8), true
+	// 231
+	case "load_imm_u16":
+		return args(), args(parser.U16), true
+	// 232
+	case "load_imm_u32":
+		return args(), args(parser.U32), true
+	// 233
+	case "load_imm_u64":
+		return args(), args(parser.U64), true
+	// 234
+	case "load_imm_u128":
+		return args(), args(parser.U128), true
+	// 235
+	case "store_imm_u8":
+		return args(parser.U8), args(), true
+	// 236
+	case "store_imm_u16":
+		return args(parser.U16), args(), true
+	// 237
+	case "store_imm_u32":
+		return args(parser.U32), args(), true
+	// 238
+	case "store_imm_u64":
+		return args(parser.U64), args(), true
+	// 239
+	case "store_imm_u128":
+		return args(parser.U128), args(), true
+	// 240
+	case "xor_u8":
+		return args(parser.U8, parser.U8), args(parser.U8), true
+	// 241
+	case "xor_u16":
+		return args(parser.U16, parser.U16), args(parser.U16), true
+	// 242
+	case "xor_u32":
+		return args(parser.U32, parser.U32), args(parser.U32), true
+	// 243
+	case "xor_u64":
+		return args(parser.U64, parser.U64), args(parser.U64), true
+	// 244
+	case "xor_u128":
+		return args(parser.U128, parser.U128), args(parser.U128), true
+	// 250
+	case "debug":
+		return args(), args(), true
+	// 251
+	case "debug_u8":
+		return args(parser.U8), args(), true
+	// 252
+	case "debug_u16":
+		return args(parser.U16), args(), true
+	// 253
+	case "debug_u32":
+		return args(parser.U32), args(), true
+	// 254
+	case "debug_u64":
+		return args(parser.U64), args(), true
+	// 255
+	case "debug_u128":
+		return args(parser.U128), args(), true
+	default:
+		return nil, nil, false
+	}
+}
@@ -0,0 +1,40 @@
+package compiler
+
+import (
+	"bootstrap/lexer"
+	"sort"
+)
+
+type Line struct {
+	Addr uint64
+	Size uint64
+	Span lexer.Span
+	Fun  string
+}
+
+func (c *Ctx) addLine(start int, bytes []uint8, span lexer.Span, f *Fun) {
+	if len(bytes) == start {
+		return
+	}
+	c.lines = append(c.lines, Line{Addr: uint64(start), Size: uint64(len(bytes) - start), Span: span, Fun: symName(f.fun)})
+}
+
+func (c *Ctx) emit(bytes []uint8, mark int, code []uint8) []uint8 {
+	for i := mark; i < len(c.lines); i++ {
+		c.lines[i].Addr += uint64(len(bytes))
+	}
+	return append(bytes, code...)
+}
+
+func (r *Result) LinesAt(addr uint64) []Line {
+	lines := []Line{}
+	for _, line := range r.Lines {
+		if addr >= line.Addr && addr < line.Addr+line.Size {
+			lines = append(lines, line)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Size < lines[j].Size
+	})
+	return lines
+}
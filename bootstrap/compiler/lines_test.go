@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+)
+
+const inlined = `fun{safe, inline} sq(u64:u64) {
+    .(u64:u64,u64) *(u64,u64:u64)
+}
+
+fun{safe} main(:) {
+    let a: u64 3u64;
+    a sq(u64:u64) drop(u64:)
+}
+`
+
+func lineAt(res *Result, line int, col int) *Line {
+	for i := range res.Lines {
+		if res.Lines[i].Span.Start.Line == line && res.Lines[i].Span.Start.Col == col {
+			return &res.Lines[i]
+		}
+	}
+	return nil
+}
+
+func positions(lines []Line) []string {
+	res := []string{}
+	for _, line := range lines {
+		if line.Span.File != "main.mvm" {
+			continue
+		}
+		res = append(res, fmt.Sprintf("%d:%d %s", line.Span.Start.Line, line.Span.Start.Col, line.Fun))
+	}
+	return res
+}
+
+func TestLines(t *testing.T) {
+	res := mustCompile(t, prelude+inlined)
+	tests := []struct {
+		name string
+		line int
+		col  int
+		want []string
+	}{
+		{"fun", 7, 1, []string{"7:1 main(:)"}},
+		{"let", 8, 5, []string{"8:16 main(:)", "8:5 main(:)", "7:1 main(:)"}},
+		{"call", 9, 7, []string{"4:5 sq(u64:u64)", "9:7 main(:)", "7:1 main(:)"}},
+		{"inlined", 4, 20, []string{"4:20 sq(u64:u64)", "9:7 main(:)", "7:1 main(:)"}},
+	}
+	for _, test := range tests {
+		line := lineAt(res, test.line, test.col)
+		if line == nil {
+			t.Errorf("%s: no line for %d:%d", test.name, test.line, test.col)
+			continue
+		}
+		got := positions(res.LinesAt(line.Addr))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	for i, line := range res.Lines {
+		if line.Size == 0 || line.Addr+line.Size > uint64(len(res.Bytes)) {
+			t.Errorf("line %+v is out of bounds", line)
+		}
+		if i != 0 && res.Lines[i-1].Addr > line.Addr {
+			t.Errorf("lines are not sorted by address at %d", i)
+		}
+	}
+
+	loaded, err := Load(res.Image().Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Lines) != len(res.Lines) {
+		t.Fatalf("loaded %d lines, want %d", len(loaded.Lines), len(res.Lines))
+	}
+	for i, line := range loaded.Lines {
+		want := res.Lines[i]
+		if line.Addr != want.Addr || line.Size != want.Size || line.Fun != want.Fun ||
+			line.Span.File != want.Span.File || line.Span.Start.Line != want.Span.Start.Line || line.Span.Start.Col != want.Span.Start.Col {
+			t.Errorf("loaded line %+v, want %+v", line, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package compiler
+
+import (
+	"bootstrap/parser"
+	"math/big"
+	"strings"
+)
+
+func numberValue(number *parser.Number) (*big.Int, bool) {
+	num, ok := new(big.Int).SetString(number.Content, number.Base)
+	if !ok {
+		return nil, false
+	}
+	bits := number.Size * 8
+	min := new(big.Int)
+	max := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if number.Signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	return num, num.Cmp(min) >= 0 && num.Cmp(max) < 0
+}
+
+func (c *Ctx) checkNumber(number *parser.Number) bool {
+	num, ok := numberValue(number)
+	if num == nil {
+		c.errorf(number.Span, "unable to convert '%s' to a number", number.Content)
+	} else if !ok {
+		c.errorf(number.Span, "the number '%s' is out of range for '%s'", numText(number), number.Typ.String(c.types))
+	}
+	return ok
+}
+
+func (c *Ctx) putNumber(buf []uint8, number *parser.Number) {
+	num, ok := numberValue(number)
+	if !ok || number.Size > len(buf) {
+		c.errorf(number.Span, "the number '%s' is out of range for '%s'", numText(number), number.Typ.String(c.types))
+		return
+	}
+	if num.Sign() < 0 {
+		num.Add(num, new(big.Int).Lsh(big.NewInt(1), uint(len(buf)*8)))
+	}
+	bytes := num.Bytes()
+	for i := range bytes {
+		buf[i] = bytes[len(bytes)-1-i]
+	}
+}
+
+func numText(number *parser.Number) string {
+	digits := strings.TrimPrefix(number.Content, "-")
+	sign := number.Content[:len(number.Content)-len(digits)]
+	switch number.Base {
+	case 16:
+		return sign + "0x" + digits
+	case 2:
+		return sign + "0b" + digits
+	}
+	return number.Content
+}
@@ -0,0 +1,97 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func le(s string) []uint8 {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+func TestWideLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []uint8
+	}{
+		{"one", "1u128", le("00000000000000000000000000000001")},
+		{"max", "0xffffffffffffffffffffffffffffffffu128", le("ffffffffffffffffffffffffffffffff")},
+		{"above u64", "18446744073709551616u128", le("00000000000000010000000000000000")},
+		{"binary", "0b1u128", le("00000000000000000000000000000001")},
+		{"min i128", "-170141183460469231731687303715884105728i128", le("80000000000000000000000000000000")},
+	}
+	for _, test := range tests {
+		if got := stackOf(t, "", test.body); !bytes.Equal(got, test.want) {
+			t.Errorf("%s: stack % x, want % x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWideLets(t *testing.T) {
+	res := mustCompile(t, prelude+"let big: u128 0x0123456789abcdef0011223344556677u128;\n\nfun{unsafe} main(:) {\n    big .asm.halt(:!)\n}\n")
+	sym := symbol(res, "big")
+	want := le("0123456789abcdef0011223344556677")
+	if sym == nil || sym.Size != 16 || !bytes.Equal(res.Bytes[sym.Addr:sym.Addr+16], want) {
+		t.Errorf("let 'big' is %+v", sym)
+	}
+	if stack, _ := run(t, res); !bytes.Equal(stack, want) {
+		t.Errorf("stack % x, want % x", stack, want)
+	}
+	_, diags := compileSrc(t, prelude+"fun{safe} main(:) {\n    0x1ffffffffffffffffffffffffffffffffu128 drop(u128:)\n}\n")
+	expectDiags(t, "too big", diags, "main.mvm:4:5: error: the number '0x1ffffffffffffffffffffffffffffffff' is out of range for 'U128'")
+}
+
+func TestSignedLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []uint8
+	}{
+		{"negative", "-5i64", le("fffffffffffffffb")},
+		{"min i8", "-128i8", le("80")},
+		{"max i8", "127i8", le("7f")},
+		{"hex", "0x7fi8", le("7f")},
+		{"negative hex", "-0x80i8", le("80")},
+		{"binary", "-0b1i16", le("ffff")},
+		{"unsigned hex", "0xffu8", le("ff")},
+	}
+	for _, test := range tests {
+		if got := stackOf(t, "", test.body); !bytes.Equal(got, test.want) {
+			t.Errorf("%s: stack % x, want % x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNumberErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"hex too big", "fun{safe} main(:) {\n    0xffi8 drop(i8:)\n}\n", "main.mvm:4:5: error: the number '0xff' is out of range for 'I8'"},
+		{"too small", "fun{safe} main(:) {\n    -129i8 drop(i8:)\n}\n", "main.mvm:4:5: error: the number '-129' is out of range for 'I8'"},
+		{"negative unsigned", "fun{safe} main(:) {\n    -1u8 drop(u8:)\n}\n", "main.mvm:4:5: error: the number '-1' is out of range for 'U8'"},
+		{"static", "let a: i8 200i8;\n\nfun{safe} main(:) {}\n", "main.mvm:3:11: error: the number '200' is out of range for 'I8'"},
+		{"static inferred", "let a: u8 256;\n\nfun{safe} main(:) {}\n", "main.mvm:3:11: error: the number '256' is out of range for 'U8'"},
+		{"static type", "let a: u8 5u64;\n\nfun{safe} main(:) {}\n", "main.mvm:3:11: error: the let 'a' is a 'U8' but its value is a 'U64'"},
+		{"static string", "let a: u64 \"s\";\n\nfun{safe} main(:) {}\n", "main.mvm:3:12: error: the let 'a' is a 'U64' but its value is a 'STRING'"},
+		{"static expr", "let a: u64 a;\n\nfun{safe} main(:) {}\n", "main.mvm:3:1: error: let 'a' can only have a string or number expr"},
+		{"inline", "fun{safe, inline} f(:) {\n    300u8 drop(u8:)\n}\n\nfun{safe} main(:) {\n    f(:) f(:)\n}\n", "main.mvm:4:5: error: the number '300' is out of range for 'U8'"},
+	}
+	for _, test := range tests {
+		_, diags := compileSrc(t, prelude+test.src)
+		expectDiags(t, test.name, diags, test.want)
+	}
+	for _, src := range []string{"let a: i8 -128i8;\n\nfun{safe} main(:) {}\n", "let a: string \"s\";\n\nfun{safe} main(:) {}\n"} {
+		mustCompile(t, prelude+src)
+	}
+}
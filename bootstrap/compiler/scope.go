@@ -0,0 +1,380 @@
+package compiler
+
+import (
+	"bootstrap/diag"
+	"bootstrap/lexer"
+	"bootstrap/parser"
+	"strings"
+)
+
+type module struct {
+	ast     parser.Ast
+	flat    bool
+	imports []*module
+	aliases map[string]*module
+	types   map[string][]*parser.Type
+	lets    map[string][]*parser.Let
+	funs    map[string][]*parser.Fun
+}
+
+type owner struct {
+	mod *module
+	pub bool
+}
+
+func isPriv(opts []*parser.Ident) bool {
+	for _, opt := range opts {
+		if opt.Content == "priv" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *module) index() {
+	m.types = make(map[string][]*parser.Type)
+	m.lets = make(map[string][]*parser.Let)
+	m.funs = make(map[string][]*parser.Fun)
+	for _, typ := range m.ast.Types {
+		m.types[typ.Ident.Content] = append(m.types[typ.Ident.Content], typ)
+	}
+	for _, let := range m.ast.Lets {
+		m.lets[let.Ident.Content] = append(m.lets[let.Ident.Content], let)
+	}
+	for _, fun := range m.ast.Funs {
+		m.funs[fun.Ident.Content] = append(m.funs[fun.Ident.Content], fun)
+	}
+}
+
+func (m *module) reach(mods []*module) []*module {
+	for _, mod := range mods {
+		if mod == m {
+			return mods
+		}
+	}
+	mods = append(mods, m)
+	for _, imp := range m.imports {
+		mods = imp.reach(mods)
+	}
+	return mods
+}
+
+func (c *Ctx) scopes(m *module, ident string) (*module, []*module, string) {
+	if i := strings.Index(ident, "."); i > 0 {
+		if alias := m.aliases[ident[:i]]; alias != nil {
+			return nil, alias.reach(nil), ident[i+1:]
+		}
+	}
+	mods := c.modules[0].reach(m.reach(nil))
+	return m, mods[1:], ident
+}
+
+func typName(typ parser.Typ) string {
+	if custom, ok := typ.(*parser.Custom); ok {
+		return custom.Ident
+	}
+	return typ.String(nil)
+}
+
+func sigOf(inputs []parser.Typ, outputs []parser.Typ) string {
+	names := []string{}
+	for _, typ := range inputs {
+		names = append(names, typName(typ))
+	}
+	sig := strings.Join(names, ",") + ":"
+	names = []string{}
+	for _, typ := range outputs {
+		names = append(names, typName(typ))
+	}
+	return sig + strings.Join(names, ",")
+}
+
+func (c *Ctx) key(used map[string]owner, ident string, sig string, m *module, pub bool) string {
+	for _, key := range []string{ident, m.ast.File + ":" + ident} {
+		prev, ok := used[key+sig]
+		if !ok {
+			used[key+sig] = owner{mod: m, pub: pub}
+			return key
+		}
+		if prev.mod == m || (prev.pub && pub) {
+			return key
+		}
+	}
+	return m.ast.File + ":" + ident
+}
+
+func (c *Ctx) ambiguous(span lexer.Span, ident string, spans []lexer.Span) {
+	d := diag.Errorf(span, "ambiguous reference to '%s'", ident)
+	for _, span := range spans {
+		d = d.Note(span, "candidate '%s'", ident)
+	}
+	c.report(d)
+}
+
+func (c *Ctx) hidden(span lexer.Span, kind string, ident string, decl lexer.Span) {
+	c.report(diag.Errorf(span, "the %s '%s' is not visible here", kind, ident).
+		Note(decl, "'%s' is declared here", ident))
+}
+
+func (c *Ctx) hiding(own *module, mods []*module) []*module {
+	if own == nil {
+		return mods
+	}
+	return c.modules
+}
+
+func (c *Ctx) lookupType(m *module, span lexer.Span, ident string) *parser.Type {
+	own, mods, name := c.scopes(m, ident)
+	if own != nil && len(own.types[name]) != 0 {
+		return own.types[name][0]
+	}
+	var found []*parser.Type
+	var spans []lexer.Span
+	for _, mod := range mods {
+		for _, typ := range mod.types[name] {
+			if !isPriv(typ.Opts) && (len(found) == 0 || found[0].Ident.Content != typ.Ident.Content) {
+				found = append(found, typ)
+				spans = append(spans, typ.Ident.Span)
+			}
+		}
+	}
+	if len(found) == 0 {
+		for _, mod := range c.hiding(own, mods) {
+			if len(mod.types[name]) != 0 {
+				c.hidden(span, "type", ident, mod.types[name][0].Ident.Span)
+				break
+			}
+		}
+		return nil
+	}
+	if len(found) > 1 {
+		c.ambiguous(span, ident, spans)
+	}
+	return found[0]
+}
+
+func (c *Ctx) lookupLet(m *module, span lexer.Span, ident string) *parser.Let {
+	own, mods, name := c.scopes(m, ident)
+	if own != nil && len(own.lets[name]) != 0 {
+		return own.lets[name][0]
+	}
+	var found []*parser.Let
+	var spans []lexer.Span
+	for _, mod := range mods {
+		for _, let := range mod.lets[name] {
+			if !isPriv(let.Opts) && (len(found) == 0 || found[0].Ident.Content != let.Ident.Content) {
+				found = append(found, let)
+				spans = append(spans, let.Ident.Span)
+			}
+		}
+	}
+	if len(found) == 0 {
+		for _, mod := range c.hiding(own, mods) {
+			if len(mod.lets[name]) != 0 {
+				c.hidden(span, "let", ident, mod.lets[name][0].Ident.Span)
+				break
+			}
+		}
+		return nil
+	}
+	if len(found) > 1 {
+		c.ambiguous(span, ident, spans)
+	}
+	return found[0]
+}
+
+func (c *Ctx) lookupFun(m *module, span lexer.Span, ident string, sig string) *parser.Fun {
+	own, mods, name := c.scopes(m, ident)
+	if own != nil {
+		for _, fun := range own.funs[name] {
+			if sigOf(fun.Inputs, fun.Outputs) == sig {
+				return fun
+			}
+		}
+	}
+	var found []*parser.Fun
+	var spans []lexer.Span
+	for _, mod := range mods {
+		for _, fun := range mod.funs[name] {
+			if !isPriv(fun.Opts) && sigOf(fun.Inputs, fun.Outputs) == sig &&
+				(len(found) == 0 || found[0].Ident.Content != fun.Ident.Content) {
+				found = append(found, fun)
+				spans = append(spans, fun.Ident.Span)
+			}
+		}
+	}
+	if len(found) == 0 {
+		for _, mod := range c.hiding(own, mods) {
+			for _, fun := range mod.funs[name] {
+				if sigOf(fun.Inputs, fun.Outputs) == sig {
+					c.hidden(span, "fun", ident+"("+sig+")", fun.Ident.Span)
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+	if len(found) > 1 {
+		c.ambiguous(span, ident+"("+sig+")", spans)
+	}
+	return found[0]
+}
+
+func (c *Ctx) scope() {
+	mods := c.modules
+	for _, m := range mods {
+		m.index()
+		for _, let := range m.ast.Lets {
+			for _, opt := range let.Opts {
+				if opt.Content != "priv" {
+					c.errorf(opt.Span, "unknown let option '%s' for let '%s'", opt.Content, let.Ident.Content)
+				}
+			}
+		}
+		for _, fun := range m.ast.Funs {
+			for _, let := range fun.Block.Lets {
+				for _, opt := range let.Opts {
+					c.errorf(opt.Span, "unknown let option '%s' for let '%s'", opt.Content, let.Ident.Content)
+				}
+			}
+		}
+	}
+
+	used := make(map[string]owner)
+	for _, first := range []bool{true, false} {
+		for _, m := range mods {
+			for _, typ := range m.ast.Types {
+				pub := m.flat && !isPriv(typ.Opts)
+				if pub == first {
+					typ.Ident.Content = c.key(used, typ.Ident.Content, "", m, pub)
+				}
+			}
+		}
+	}
+	for _, m := range mods {
+		for _, typ := range m.ast.Types {
+			c.scopeTyps(m, typ.Ident.Span, typ.Fields)
+		}
+		for _, let := range m.ast.Lets {
+			c.scopeTyps(m, let.Span, []parser.Typ{let.Typ})
+		}
+		for _, fun := range m.ast.Funs {
+			c.scopeTyps(m, fun.Ident.Span, fun.Inputs)
+			c.scopeTyps(m, fun.Ident.Span, fun.Outputs)
+			for _, let := range fun.Block.Lets {
+				c.scopeTyps(m, let.Span, []parser.Typ{let.Typ})
+				c.scopeExprTyps(m, let.Exprs)
+			}
+			c.scopeExprTyps(m, fun.Block.Exprs)
+		}
+	}
+
+	used = make(map[string]owner)
+	for _, first := range []bool{true, false} {
+		for _, m := range mods {
+			for _, let := range m.ast.Lets {
+				pub := m.flat && !isPriv(let.Opts)
+				if pub == first {
+					let.Ident.Content = c.key(used, let.Ident.Content, "", m, pub)
+				}
+			}
+		}
+	}
+	used = make(map[string]owner)
+	for _, first := range []bool{true, false} {
+		for _, m := range mods {
+			for _, fun := range m.ast.Funs {
+				pub := m.flat && !isPriv(fun.Opts)
+				if pub == first {
+					sig := "(" + sigOf(fun.Inputs, fun.Outputs) + ")"
+					fun.Ident.Content = c.key(used, fun.Ident.Content, sig, m, pub)
+				}
+			}
+		}
+	}
+
+	for _, m := range mods {
+		for _, let := range m.ast.Lets {
+			c.scopeExprs(m, nil, let.Exprs)
+		}
+		for _, fun := range m.ast.Funs {
+			locals := make(map[string]bool)
+			for _, let := range fun.Block.Lets {
+				locals[let.Ident.Content] = true
+			}
+			for _, let := range fun.Block.Lets {
+				c.scopeExprs(m, locals, let.Exprs)
+			}
+			c.scopeExprs(m, locals, fun.Block.Exprs)
+		}
+	}
+}
+
+func (c *Ctx) scopeTyps(m *module, span lexer.Span, typs []parser.Typ) {
+	for _, typ := range typs {
+		if custom, ok := typ.(*parser.Custom); ok {
+			if decl := c.lookupType(m, span, custom.Ident); decl != nil {
+				custom.Ident = decl.Ident.Content
+			}
+		}
+	}
+}
+
+func (c *Ctx) scopeExprTyps(m *module, exprs []parser.Expr) {
+	for _, expr := range exprs {
+		if call := expr.AsCall(); call != nil {
+			c.scopeTyps(m, call.Span, call.Inputs)
+			c.scopeTyps(m, call.Span, call.Outputs)
+		} else if ifel := expr.AsIf(); ifel != nil {
+			c.scopeExprTyps(m, ifel.Con)
+			c.scopeExprTyps(m, ifel.Exprs)
+			c.scopeExprTyps(m, ifel.Else)
+		} else if while := expr.AsWhile(); while != nil {
+			c.scopeExprTyps(m, while.Con)
+			c.scopeExprTyps(m, while.Exprs)
+		} else if wrap := expr.AsWrap(); wrap != nil {
+			c.scopeTyps(m, wrap.Span, []parser.Typ{wrap.Typ})
+		} else if addr := expr.AsAddr(); addr != nil && addr.Call != nil {
+			c.scopeTyps(m, addr.Span, addr.Call.Inputs)
+			c.scopeTyps(m, addr.Span, addr.Call.Outputs)
+		}
+	}
+}
+
+func (c *Ctx) scopeIdent(m *module, locals map[string]bool, ident *parser.Ident) {
+	if locals[ident.Content] {
+		return
+	}
+	if let := c.lookupLet(m, ident.Span, ident.Content); let != nil {
+		ident.Content = let.Ident.Content
+	}
+}
+
+func (c *Ctx) scopeCall(m *module, call *parser.Call) {
+	if fun := c.lookupFun(m, call.Span, call.Ident.Content, sigOf(call.Inputs, call.Outputs)); fun != nil {
+		call.Ident.Content = fun.Ident.Content
+	}
+}
+
+func (c *Ctx) scopeExprs(m *module, locals map[string]bool, exprs []parser.Expr) {
+	for _, expr := range exprs {
+		if ident := expr.AsIdent(); ident != nil {
+			c.scopeIdent(m, locals, ident)
+		} else if call := expr.AsCall(); call != nil {
+			c.scopeCall(m, call)
+		} else if ifel := expr.AsIf(); ifel != nil {
+			c.scopeExprs(m, locals, ifel.Con)
+			c.scopeExprs(m, locals, ifel.Exprs)
+			c.scopeExprs(m, locals, ifel.Else)
+		} else if while := expr.AsWhile(); while != nil {
+			c.scopeExprs(m, locals, while.Con)
+			c.scopeExprs(m, locals, while.Exprs)
+		} else if addr := expr.AsAddr(); addr != nil {
+			if addr.Ident != nil {
+				c.scopeIdent(m, locals, addr.Ident)
+			} else {
+				c.scopeCall(m, addr.Call)
+			}
+		}
+	}
+}
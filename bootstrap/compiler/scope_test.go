@@ -0,0 +1,89 @@
+package compiler
+
+import "testing"
+
+func TestScope(t *testing.T) {
+	helper := "fun{safe} helper(:) {\n    \"%s\\n\" print(string:)\n}\n"
+	tests := []struct {
+		name string
+		srcs map[string]string
+		out  string
+		want string
+	}{
+		{
+			"qualified",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\" as x;\n\nfun{safe} main(:) {\n    x.helper(:)\n}\n",
+				"x.mvm":    prelude + "fun{safe} helper(:) {\n    \"x\\n\" print(string:)\n}\n",
+			},
+			"x\n", "",
+		},
+		{
+			"qualified and local",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\" as x;\n\nfun{safe} main(:) {\n    x.helper(:) helper(:)\n}\n\nfun{safe} helper(:) {\n    \"main\\n\" print(string:)\n}\n",
+				"x.mvm":    prelude + "fun{safe} helper(:) {\n    \"x\\n\" print(string:)\n}\n",
+			},
+			"x\nmain\n", "",
+		},
+		{
+			"private lets per file",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\";\n\nlet{priv} msg: string \"main\\n\";\n\nfun{safe} main(:) {\n    msg print(string:) helper(:)\n}\n",
+				"x.mvm":    prelude + "let{priv} msg: string \"x\\n\";\n\nfun{safe} helper(:) {\n    msg print(string:)\n}\n",
+			},
+			"main\nx\n", "",
+		},
+		{
+			"private fun",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\";\n\nfun{safe} main(:) {\n    helper(:)\n}\n",
+				"x.mvm":    prelude + "fun{safe, priv} helper(:) {\n    \"x\\n\" print(string:)\n}\n",
+			},
+			"", "main.mvm:6:5: error: the fun 'helper(:)' is not visible here\n\tx.mvm:3:17: note: 'helper(:)' is declared here",
+		},
+		{
+			"public clash",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\";\nimport \"y.mvm\";\n\nfun{safe} main(:) {\n    helper(:)\n}\n",
+				"x.mvm":    prelude + helper,
+				"y.mvm":    prelude + helper,
+			},
+			"", "y.mvm:3:11: error: the fun 'helper(:)' already exists\n\tx.mvm:3:11: note: previous definition of 'helper(:)'",
+		},
+		{
+			"duplicate alias",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\" as x;\nimport \"y.mvm\" as x;\n\nfun{safe} main(:) {}\n",
+				"x.mvm":    "",
+				"y.mvm":    "",
+			},
+			"", "main.mvm:4:19: error: the import alias 'x' already exists",
+		},
+		{
+			"dotted alias",
+			map[string]string{
+				"main.mvm": prelude + "import \"x.mvm\" as x.y;\n\nfun{safe} main(:) {}\n",
+				"x.mvm":    "",
+			},
+			"", "main.mvm:3:19: error: the import alias 'x.y' can't contain a '.'",
+		},
+		{
+			"duplicate in one file",
+			map[string]string{
+				"main.mvm": prelude + "let a: u64 1u64;\nlet a: u64 2u64;\n\nfun{safe} main(:) {}\n",
+			},
+			"", "main.mvm:4:5: error: the let 'a' already exists\n\tmain.mvm:3:5: note: previous definition of 'a'",
+		},
+	}
+	for _, test := range tests {
+		res, diags := compileFiles(t, test.srcs)
+		expectDiags(t, test.name, diags, test.want)
+		if res == nil {
+			continue
+		}
+		if _, out := run(t, res); out != test.out {
+			t.Errorf("%s: output %q, want %q", test.name, out, test.out)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package compiler
 
 import (
+	"bootstrap/diag"
+	"bootstrap/lexer"
 	"bootstrap/parser"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 func (c *Ctx) stackPrefix(stack []parser.Typ, typs ...parser.Typ) (bool, []parser.Typ) {
@@ -19,6 +23,33 @@ func (c *Ctx) stackPrefix(stack []parser.Typ, typs ...parser.Typ) (bool, []parse
 	return false, stack[:stack_len-typs_len]
 }
 
+func (c *Ctx) stackNotes(d diag.Diagnostic, left string, lstack []parser.Typ, right string, rstack []parser.Typ) diag.Diagnostic {
+	rows := [][2]string{{left, right}}
+	width := len(left)
+	for i := 0; i < len(lstack) || i < len(rstack); i++ {
+		row := [2]string{}
+		if i < len(lstack) {
+			row[0] = lstack[len(lstack)-1-i].String(c.types)
+		}
+		if i < len(rstack) {
+			row[1] = rstack[len(rstack)-1-i].String(c.types)
+		}
+		if len(row[0]) > width {
+			width = len(row[0])
+		}
+		rows = append(rows, row)
+	}
+	for _, row := range rows {
+		d = d.Note(lexer.Span{}, "%s", strings.TrimRight(fmt.Sprintf("%-*s  %s", width, row[0], row[1]), " "))
+	}
+	return d
+}
+
+func (c *Ctx) fatalStack(span lexer.Span, expected []parser.Typ, actual []parser.Typ, format string, args ...interface{}) {
+	c.report(c.stackNotes(diag.Errorf(span, format, args...), "expected", expected, "actual", actual))
+	panic(bailout{})
+}
+
 func stackPrefixSimple(c *Ctx, stack int, typs ...parser.Typ) (bool, int) {
 	typs_len := c.typsSize(typs)
 	if typs_len > stack {
@@ -37,11 +68,13 @@ func containsNever(ts []parser.Typ) bool {
 }
 
 func (f *Fun) typeCheck(c *Ctx, simple bool) {
+	defer c.catch()
+
 	if containsNever(f.fun.Inputs) {
-		panic(fmt.Sprintf("the never type can't be used as an input argument in '%s'", f.makeFunIdent(c)))
+		c.fatalf(f.fun.Ident.Span, "the never type can't be used as an input argument in '%s'", f.makeFunIdent(c))
 	}
 	if containsNever(f.fun.Outputs) && len(f.fun.Outputs) != 1 {
-		panic(fmt.Sprintf("the never type has to be the only output of '%s'", f.makeFunIdent(c)))
+		c.fatalf(f.fun.Ident.Span, "the never type has to be the only output of '%s'", f.makeFunIdent(c))
 	}
 
 	var ret bool
@@ -55,19 +88,25 @@ func (f *Fun) typeCheck(c *Ctx, simple bool) {
 			stack = f.checkStackAsm(c, stack)
 		} else {
 			for _, let := range f.fun.Block.Lets {
-				stackl := len(stack)
+				prev := append([]parser.Typ{}, stack...)
+				before := c.traceTyps(stack)
 				never, ret, stack = f.checkStackExprs(c, stack, let.Exprs)
 				if ret {
-					panic(fmt.Sprintf("the let in fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+					c.fatalf(let.Span, "the let in fun '%s' does not have a valid stack", f.makeFunIdent(c))
 				}
 				if never {
 					return
 				}
 				err, nstack := c.stackPrefix(stack, let.Typ)
-				if err || stackl < len(nstack) {
-					panic(fmt.Sprintf("the let in fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+				if err || len(prev) < len(nstack) {
+					expected := append(prev, let.Typ)
+					if len(let.Exprs) == 0 {
+						expected = []parser.Typ{let.Typ}
+					}
+					c.fatalStack(let.Span, expected, stack, "the let in fun '%s' does not have a valid stack", f.makeFunIdent(c))
 				}
 				stack = nstack
+				c.trace(f, let.Span, before, stack)
 			}
 			never, _, stack = f.checkStackExprs(c, stack, f.fun.Block.Exprs)
 			if never {
@@ -76,7 +115,7 @@ func (f *Fun) typeCheck(c *Ctx, simple bool) {
 		}
 		err, rest := c.stackPrefix(stack, f.fun.Outputs...)
 		if err || len(rest) != 0 {
-			panic(fmt.Sprintf("the fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+			c.fatalStack(f.fun.Block.Span, f.fun.Outputs, stack, "the fun '%s' does not have a valid stack", f.makeFunIdent(c))
 		}
 	}
 }
@@ -93,14 +132,14 @@ func (f *Fun) typeCheckSimple(c *Ctx) {
 			stackl := stack
 			never, ret, stack = f.checkStackExprsSimple(c, stack, let.Exprs)
 			if ret {
-				panic(fmt.Sprintf("the let in fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+				c.fatalf(let.Span, "the let in fun '%s' does not have a valid stack", f.makeFunIdent(c))
 			}
 			if never {
 				return
 			}
 			err, nstack := stackPrefixSimple(c, stack, let.Typ)
 			if err || stackl < nstack {
-				panic(fmt.Sprintf("the let in fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+				c.fatalf(let.Span, "the let in fun '%s' does not have a valid stack", f.makeFunIdent(c))
 			}
 			stack = nstack
 		}
@@ -112,32 +151,34 @@ func (f *Fun) typeCheckSimple(c *Ctx) {
 	err, stack := stackPrefixSimple(c, stack, f.fun.Outputs...)
 
 	if err || stack != 0 {
-		panic(fmt.Sprintf("the fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+		c.fatalf(f.fun.Block.Span, "the fun '%s' does not have a valid stack", f.makeFunIdent(c))
 	}
 }
 
-func (f *Fun) checkStackCall(c *Ctx, stack []parser.Typ, inputs []parser.Typ, outputs []parser.Typ) []parser.Typ {
-	err, stack := c.stackPrefix(stack, inputs...)
+func (f *Fun) checkStackCall(c *Ctx, stack []parser.Typ, call *parser.Call) []parser.Typ {
+	err, rest := c.stackPrefix(stack, call.Inputs...)
 	if err {
-		panic(fmt.Sprintf("the fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+		c.fatalStack(call.Span, call.Inputs, stack, "the fun '%s' does not have a valid stack", f.makeFunIdent(c))
 	}
-	return append(stack, outputs...)
+	stack = rest
+	return append(stack, call.Outputs...)
 }
 
 func (f *Fun) checkStackIfel(c *Ctx, stack []parser.Typ, ifel *parser.If) (bool, bool, []parser.Typ) {
 	never, ret, stack := f.checkStackExprs(c, stack, ifel.Con)
 	if never || ret {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(ifel.Span, "the if in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
-	err, stack := c.stackPrefix(stack, parser.BOOL)
+	err, rest := c.stackPrefix(stack, parser.BOOL)
 	if err {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalStack(ifel.Span, []parser.Typ{parser.BOOL}, stack, "the if in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
-	iNever, ret, iStack := f.checkStackExprs(c, stack, ifel.Exprs)
+	stack = rest
+	iNever, ret, iStack := f.checkStackExprs(c, append([]parser.Typ{}, stack...), ifel.Exprs)
 	if ret {
 		return iNever, true, iStack
 	}
-	eNever, ret, eStack := f.checkStackExprs(c, stack, ifel.Else)
+	eNever, ret, eStack := f.checkStackExprs(c, append([]parser.Typ{}, stack...), ifel.Else)
 	if ret {
 		return eNever, true, eStack
 	}
@@ -152,7 +193,9 @@ func (f *Fun) checkStackIfel(c *Ctx, stack []parser.Typ, ifel *parser.If) (bool,
 	}
 	err, rstack := c.stackPrefix(iStack, eStack...)
 	if err || len(rstack) != 0 {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid expression stack", f.makeFunIdent(c)))
+		c.report(c.stackNotes(diag.Errorf(ifel.Span, "the if in '%s' does not have a valid expression stack", f.makeFunIdent(c)),
+			"if", iStack, "else", eStack))
+		panic(bailout{})
 	}
 	return false, false, iStack
 }
@@ -160,16 +203,20 @@ func (f *Fun) checkStackIfel(c *Ctx, stack []parser.Typ, ifel *parser.If) (bool,
 func (f *Fun) checkStackWhile(c *Ctx, stack []parser.Typ, while *parser.While) (bool, []parser.Typ) {
 	never, ret, stack := f.checkStackExprs(c, stack, while.Con)
 	if ret || never {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(while.Span, "the while in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
-	err, stack := c.stackPrefix(stack, parser.BOOL)
+	err, rest := c.stackPrefix(stack, parser.BOOL)
 	if err {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalStack(while.Span, []parser.Typ{parser.BOOL}, stack, "the while in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
-	never, ret, wStack := f.checkStackExprs(c, stack, while.Exprs)
+	stack = rest
+	never, ret, wStack := f.checkStackExprs(c, append([]parser.Typ{}, stack...), while.Exprs)
 	err, rStack := c.stackPrefix(stack, wStack...)
-	if ret || err || len(rStack) != 0 {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid expression stack", f.makeFunIdent(c)))
+	if ret {
+		c.fatalf(while.Span, "the while in '%s' does not have a valid expression stack", f.makeFunIdent(c))
+	}
+	if err || len(rStack) != 0 {
+		c.fatalStack(while.Span, stack, wStack, "the while in '%s' does not have a valid expression stack", f.makeFunIdent(c))
 	}
 	return never, wStack
 }
@@ -189,24 +236,29 @@ func (f *Fun) checkStackExprs(c *Ctx, stack []parser.Typ, exprs []parser.Expr) (
 		wrap := expr.AsWrap()
 		addr := expr.AsAddr()
 		ret := expr.AsReturn()
+		hole := expr.AsHole()
+		before := c.traceTyps(stack)
 		if ident != nil {
-			ident := ident.Content
-			let := f.info.lets[ident]
+			let := f.getLet(c, ident.Content)
 			if let == nil {
-				let = c.lets[ident]
+				panic(bailout{})
 			}
 			stack = append(stack, let.let.Typ)
 		} else if call != nil {
 			if containsNever(call.Outputs) {
+				c.trace(f, expr.GetSpan(), before, call.Outputs)
 				return true, false, []parser.Typ{}
 			}
-			stack = f.checkStackCall(c, stack, call.Inputs, call.Outputs)
+			stack = f.checkStackCall(c, stack, call)
 		} else if number != nil {
-			stack = append(stack, number.Typ)
+			stack = append(stack, numberTyp(number))
 		} else if str != nil {
 			stack = append(stack, parser.STRING)
 		} else if ifel != nil {
 			never, r, stack = f.checkStackIfel(c, stack, ifel)
+			if r || never {
+				c.trace(f, expr.GetSpan(), before, stack)
+			}
 			if r {
 				return never, true, stack
 			}
@@ -216,6 +268,7 @@ func (f *Fun) checkStackExprs(c *Ctx, stack []parser.Typ, exprs []parser.Expr) (
 		} else if while != nil {
 			never, stack = f.checkStackWhile(c, stack, while)
 			if never {
+				c.trace(f, expr.GetSpan(), before, []parser.Typ{})
 				return true, false, []parser.Typ{}
 			}
 		} else if unwrap != nil {
@@ -224,25 +277,128 @@ func (f *Fun) checkStackExprs(c *Ctx, stack []parser.Typ, exprs []parser.Expr) (
 				sub := stack[last].Sub(c.types)
 				stack = append(stack[:last], sub...)
 			} else {
-				panic(fmt.Sprintf("can't unwrap empty stack in '%s'", f.makeFunIdent(c)))
+				c.fatalf(unwrap.Span, "can't unwrap empty stack in '%s'", f.makeFunIdent(c))
 			}
 		} else if wrap != nil {
 			err, nstack := c.stackPrefix(stack, wrap.Typ.Sub(c.types)...)
 			if err {
-				panic(fmt.Sprintf("can't wrap stack in '%s'", f.makeFunIdent(c)))
+				c.fatalf(wrap.Span, "can't wrap stack in '%s'", f.makeFunIdent(c))
 			}
 			stack = append(nstack, wrap.Typ)
 		} else if addr != nil {
 			stack = append(stack, parser.U64)
 		} else if ret != nil {
+			c.trace(f, expr.GetSpan(), before, stack)
 			return false, true, stack
+		} else if hole != nil {
+			c.trace(f, expr.GetSpan(), before, stack)
+			f.hole(c, hole, stack)
 		} else {
 			panic("unreachable")
 		}
+		c.trace(f, expr.GetSpan(), before, stack)
 	}
 	return false, false, stack
 }
 
+const maxHoleCandidates = 20
+
+func hasOpt(opts []*parser.Ident, name string) bool {
+	for _, opt := range opts {
+		if opt.Content == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Ctx) consumes(stack []parser.Typ, typs []parser.Typ) bool {
+	if len(typs) > len(stack) {
+		return false
+	}
+	rest := len(stack) - len(typs)
+	for i, typ := range typs {
+		if l, ok := stack[rest+i].(*literal); ok {
+			if !l.fits(typ) {
+				return false
+			}
+		} else if stack[rest+i].String(c.types) != typ.String(c.types) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasHole(exprs []parser.Expr) bool {
+	for _, expr := range exprs {
+		if expr.AsHole() != nil {
+			return true
+		} else if ifel := expr.AsIf(); ifel != nil && (hasHole(ifel.Con) || hasHole(ifel.Exprs) || hasHole(ifel.Else)) {
+			return true
+		} else if while := expr.AsWhile(); while != nil && (hasHole(while.Con) || hasHole(while.Exprs)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fun) hasHole() bool {
+	for _, let := range f.fun.Block.Lets {
+		if hasHole(let.Exprs) {
+			return true
+		}
+	}
+	return hasHole(f.fun.Block.Exprs)
+}
+
+func (f *Fun) hole(c *Ctx, hole *parser.Hole, stack []parser.Typ) {
+	file := f.fun.Span.File
+	unsafe := f.info.unsafe || f.info.safe
+	candidates := []*parser.Fun{}
+	for ident, g := range c.funs {
+		fun := g.fun
+		if len(fun.Inputs) == 0 || ident == c.start || !visible(file, fun.Span.File, fun.Ident.Content, fun.Opts) {
+			continue
+		}
+		if hasOpt(fun.Opts, "unsafe") && !unsafe {
+			continue
+		}
+		if c.consumes(stack, fun.Inputs) {
+			candidates = append(candidates, fun)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(a.Inputs) != len(b.Inputs) {
+			return len(a.Inputs) > len(b.Inputs)
+		}
+		if strings.HasPrefix(a.Ident.Content, ".") != strings.HasPrefix(b.Ident.Content, ".") {
+			return !strings.HasPrefix(a.Ident.Content, ".")
+		}
+		if a.Ident.Content != b.Ident.Content {
+			return a.Ident.Content < b.Ident.Content
+		}
+		if srcTyps(a.Inputs) != srcTyps(b.Inputs) {
+			return srcTyps(a.Inputs) < srcTyps(b.Inputs)
+		}
+		return srcTyps(a.Outputs) < srcTyps(b.Outputs)
+	})
+	d := diag.Errorf(hole.Span, "found a hole in '%s' with the stack %s", f.makeFunIdent(c), FormatStack(c.typNames(stack)))
+	if len(candidates) == 0 {
+		d = d.Note(lexer.Span{}, "no known fun can consume the top of the stack")
+	}
+	for i, fun := range candidates {
+		if i == maxHoleCandidates {
+			d = d.Note(lexer.Span{}, "and %d more", len(candidates)-i)
+			break
+		}
+		name := baseName(fun.Ident.Content, fun.Span.File)
+		d = d.Note(fun.Ident.Span, "'%s' can consume the top of the stack", c.makeFunIdent(name, fun.Inputs, fun.Outputs))
+	}
+	c.report(d)
+	panic(bailout{})
+}
+
 func (c *Ctx) typsSize(typs []parser.Typ) int {
 	size := 0
 	for _, inp := range typs {
@@ -251,25 +407,25 @@ func (c *Ctx) typsSize(typs []parser.Typ) int {
 	return size
 }
 
-func (f *Fun) checkStackCallSimple(c *Ctx, stack int, inputs []parser.Typ, outputs []parser.Typ) int {
-	err, stack := stackPrefixSimple(c, stack, inputs...)
+func (f *Fun) checkStackCallSimple(c *Ctx, stack int, call *parser.Call) int {
+	err, stack := stackPrefixSimple(c, stack, call.Inputs...)
 	if err {
-		panic(fmt.Sprintf("the fun '%s' does not have a valid stack", f.makeFunIdent(c)))
+		c.fatalf(call.Span, "the fun '%s' does not have a valid stack", f.makeFunIdent(c))
 	}
-	return stack + c.typsSize(outputs)
+	return stack + c.typsSize(call.Outputs)
 }
 
 func (f *Fun) checkStackIfelSimple(c *Ctx, stack int, ifel *parser.If) (bool, bool, int) {
 	never, ret, stack := f.checkStackExprsSimple(c, stack, ifel.Con)
 	if ret {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(ifel.Span, "the if in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
 	if never {
 		return true, false, 0
 	}
 	err, stack := stackPrefixSimple(c, stack, parser.BOOL)
 	if err {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(ifel.Span, "the if in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
 	iNever, ret, iStack := f.checkStackExprsSimple(c, stack, ifel.Exprs)
 	if ret {
@@ -289,7 +445,7 @@ func (f *Fun) checkStackIfelSimple(c *Ctx, stack int, ifel *parser.If) (bool, bo
 		return false, false, iStack
 	}
 	if iStack != eStack {
-		panic(fmt.Sprintf("the if in '%s' does not have a valid expression stack", f.makeFunIdent(c)))
+		c.fatalf(ifel.Span, "the if in '%s' does not have a valid expression stack", f.makeFunIdent(c))
 	}
 	return false, false, iStack
 }
@@ -297,15 +453,15 @@ func (f *Fun) checkStackIfelSimple(c *Ctx, stack int, ifel *parser.If) (bool, bo
 func (f *Fun) checkStackWhileSimple(c *Ctx, stack int, while *parser.While) (bool, int) {
 	never, ret, stack := f.checkStackExprsSimple(c, stack, while.Con)
 	if ret || never {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(while.Span, "the while in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
 	err, stack := stackPrefixSimple(c, stack, parser.BOOL)
 	if err {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid condition stack", f.makeFunIdent(c)))
+		c.fatalf(while.Span, "the while in '%s' does not have a valid condition stack", f.makeFunIdent(c))
 	}
 	never, ret, wStack := f.checkStackExprsSimple(c, stack, while.Exprs)
 	if ret || err || wStack != 0 {
-		panic(fmt.Sprintf("the while in '%s' does not have a valid expression stack", f.makeFunIdent(c)))
+		c.fatalf(while.Span, "the while in '%s' does not have a valid expression stack", f.makeFunIdent(c))
 	}
 	return never, wStack
 }
@@ -325,20 +481,20 @@ func (f *Fun) checkStackExprsSimple(c *Ctx, stack int, exprs []parser.Expr) (boo
 		wrap := expr.AsWrap()
 		addr := expr.AsAddr()
 		ret := expr.AsReturn()
+		hole := expr.AsHole()
 		if ident != nil {
-			ident := ident.Content
-			let := f.info.lets[ident]
+			let := f.getLet(c, ident.Content)
 			if let == nil {
-				let = c.lets[ident]
+				panic(bailout{})
 			}
 			stack += let.let.Typ.Size(c.types)
 		} else if call != nil {
 			if containsNever(call.Outputs) {
 				return true, false, 0
 			}
-			stack = f.checkStackCallSimple(c, stack, call.Inputs, call.Outputs)
+			stack = f.checkStackCallSimple(c, stack, call)
 		} else if number != nil {
-			stack += number.Typ.Size(c.types)
+			stack += numberTyp(number).Size(c.types)
 		} else if str != nil {
 			stack += parser.STRING.Size(c.types)
 		} else if ifel != nil {
@@ -355,13 +511,15 @@ func (f *Fun) checkStackExprsSimple(c *Ctx, stack int, exprs []parser.Expr) (boo
 				return true, false, 0
 			}
 		} else if unwrap != nil {
-			panic(fmt.Sprintf("can't unwrap in simple type check fun '%s'", f.makeFunIdent(c)))
+			c.fatalf(unwrap.Span, "can't unwrap in simple type check fun '%s'", f.makeFunIdent(c))
 		} else if wrap != nil {
-			panic(fmt.Sprintf("can't wrap in simple type check fun '%s'", f.makeFunIdent(c)))
+			c.fatalf(wrap.Span, "can't wrap in simple type check fun '%s'", f.makeFunIdent(c))
 		} else if addr != nil {
 			stack += 8
 		} else if ret != nil {
 			return false, true, stack
+		} else if hole != nil {
+			c.fatalf(hole.Span, "found a hole in '%s' with %d bytes on the stack", f.makeFunIdent(c), stack)
 		} else {
 			panic("unreachable")
 		}
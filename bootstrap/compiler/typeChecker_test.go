@@ -0,0 +1,54 @@
+package compiler
+
+import "testing"
+
+const holes = `type Ptr(u64);
+
+type Id(u8);
+
+fun{safe} to(Ptr:u64) {
+    .unwrap
+}
+
+fun{safe} to(Ptr:u8) {
+    .unwrap to(u64:u8)
+}
+
+fun{safe} to(Ptr:u32) {
+    .unwrap to(u64:u32)
+}
+
+fun{safe} at(string,Ptr:u8) {
+    to(Ptr:u8) swap(string,u8:u8,string) drop(string:)
+}
+`
+
+func TestHole(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"candidates",
+			"\"s\" 0u64 .wrap(Ptr) _",
+			"main.mvm:24:25: error: found a hole in 'main(:)' with the stack [STRING, Ptr]\n" +
+				"\tmain.mvm:19:11: note: 'at(STRING,Ptr:U8)' can consume the top of the stack\n" +
+				"\tmain.mvm:15:11: note: 'to(Ptr:U32)' can consume the top of the stack\n" +
+				"\tmain.mvm:7:11: note: 'to(Ptr:U64)' can consume the top of the stack\n" +
+				"\tmain.mvm:11:11: note: 'to(Ptr:U8)' can consume the top of the stack",
+		},
+		{
+			"no candidates",
+			"0u8 .wrap(Id) _",
+			"main.mvm:24:19: error: found a hole in 'main(:)' with the stack [Id]\n" +
+				"\tnote: no known fun can consume the top of the stack",
+		},
+	}
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			_, diags := compileSrc(t, prelude+holes+"\nfun{safe} main(:) {\n    "+test.body+"\n}\n")
+			expectDiags(t, test.name, diags, test.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package diag
+
+import (
+	"bootstrap/lexer"
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	ERROR   Severity = "error"
+	WARNING Severity = "warning"
+	NOTE    Severity = "note"
+)
+
+type Note struct {
+	Span lexer.Span
+	Msg  string
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Msg      string
+	Span     lexer.Span
+	Notes    []Note
+}
+
+func Errorf(span lexer.Span, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: ERROR, Msg: fmt.Sprintf(format, args...), Span: span}
+}
+
+func Warningf(span lexer.Span, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: WARNING, Msg: fmt.Sprintf(format, args...), Span: span}
+}
+
+func (d Diagnostic) Note(span lexer.Span, format string, args ...interface{}) Diagnostic {
+	d.Notes = append(append([]Note{}, d.Notes...), Note{Span: span, Msg: fmt.Sprintf(format, args...)})
+	return d
+}
+
+func (d Diagnostic) String() string {
+	var buf strings.Builder
+	if d.Span != (lexer.Span{}) {
+		fmt.Fprintf(&buf, "%s: ", d.Span)
+	}
+	fmt.Fprintf(&buf, "%s: %s", d.Severity, d.Msg)
+	for _, note := range d.Notes {
+		buf.WriteString("\n\t")
+		if note.Span != (lexer.Span{}) {
+			fmt.Fprintf(&buf, "%s: ", note.Span)
+		}
+		fmt.Fprintf(&buf, "%s: %s", NOTE, note.Msg)
+	}
+	return buf.String()
+}
+
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == ERROR {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bootstrap/compiler"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func loadResult(input string, includes paths, isaFile string) *compiler.Result {
+	if strings.HasSuffix(input, ".mvm") || strings.HasSuffix(input, ".mvasm") {
+		return build(input, includes, isaFile, false)
+	}
+	dat, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read '%s'\n", input)
+		os.Exit(1)
+	}
+	res, err := compiler.Load(dat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid image '%s': %s\n", input, err)
+		os.Exit(1)
+	}
+	return res
+}
+
+func disasm(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("disasm", &includes, &isaFile)
+	set.Parse(args)
+	if set.NArg() != 1 {
+		usageError(set, "expected a single input")
+	}
+	input := set.Arg(0)
+	res := loadResult(input, includes, isaFile)
+	ext := loadIsa(isaFile)
+	if compiler.Disassemble(os.Stdout, res.Bytes, res.Symbols, res.Strings, ext) != nil {
+		os.Exit(1)
+	}
+}
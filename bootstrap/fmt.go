@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bootstrap/format"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func fmtFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s'", path)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(file, ".mvm") {
+				files = append(files, file)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s'", path)
+		}
+	}
+	return files, nil
+}
+
+func fmtFile(file string, check bool) (bool, bool) {
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read '%s'\n", file)
+		return false, false
+	}
+	out, diags := format.Source(file, string(dat))
+	printDiags(diags)
+	if out == "" && len(diags) != 0 {
+		return false, false
+	}
+	if out == string(dat) {
+		return true, true
+	}
+	if check {
+		fmt.Println(file)
+		return true, false
+	}
+	if os.WriteFile(file, []uint8(out), 0644) != nil {
+		fmt.Fprintf(os.Stderr, "unable to write '%s'\n", file)
+		return false, false
+	}
+	return true, true
+}
+
+func fmtCmd(args []string) {
+	set := newFlagSet("fmt", nil, nil)
+	check := set.Bool("check", false, "list unformatted files instead of rewriting them")
+	set.Parse(args)
+	if set.NArg() == 0 {
+		dat, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "unable to read stdin")
+			os.Exit(1)
+		}
+		out, diags := format.Source("<stdin>", string(dat))
+		printDiags(diags)
+		if out == "" && len(diags) != 0 {
+			os.Exit(1)
+		}
+		if *check {
+			if out != string(dat) {
+				fmt.Println("<stdin>")
+				os.Exit(1)
+			}
+			return
+		}
+		os.Stdout.WriteString(out)
+		return
+	}
+	files, err := fmtFiles(set.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	failed := false
+	for _, file := range files {
+		ok, formatted := fmtFile(file, *check)
+		failed = failed || !ok || !formatted
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
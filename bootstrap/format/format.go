@@ -0,0 +1,391 @@
+package format
+
+import (
+	"bootstrap/diag"
+	"bootstrap/lexer"
+	"bootstrap/parser"
+	"sort"
+	"strings"
+)
+
+const indent = "    "
+
+type printer struct {
+	buf      strings.Builder
+	comments []lexer.Token
+	next     int
+	indent   int
+	bol      bool
+	first    bool
+	force    bool
+	lastLine int
+}
+
+func Source(file string, src string) (string, []diag.Diagnostic) {
+	ast, diags := parser.ParseMode(lexer.New(file, src), parser.Groups)
+	if diag.HasErrors(diags) {
+		return "", diags
+	}
+	return Ast(ast), diags
+}
+
+type decl struct {
+	span  lexer.Span
+	kind  string
+	print func()
+}
+
+func Ast(ast parser.Ast) string {
+	p := &printer{comments: ast.Comments, bol: true, first: true}
+	decls := []decl{}
+	for _, imp := range ast.Imports {
+		imp := imp
+		decls = append(decls, decl{imp.Span, "import", func() { p.imp(imp) }})
+	}
+	for _, let := range ast.Lets {
+		let := let
+		decls = append(decls, decl{let.Span, "let", func() { p.let(let) }})
+	}
+	for _, typ := range ast.Types {
+		typ := typ
+		decls = append(decls, decl{typ.Span, "type", func() { p.typ(typ) }})
+	}
+	for _, fun := range ast.Funs {
+		fun := fun
+		decls = append(decls, decl{fun.Span, "fun", func() { p.fun(fun) }})
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].span.Start.Offset < decls[j].span.Start.Offset
+	})
+	for i, d := range decls {
+		p.force = i > 0 && (d.kind != decls[i-1].kind || d.kind == "fun" || d.kind == "type")
+		p.flush(d.span.Start.Offset)
+		p.start(d.span.Start.Line)
+		d.print()
+		p.lastLine = d.span.End.Line
+	}
+	p.flush(-1)
+	p.breakLine()
+	return strings.TrimLeft(p.buf.String(), "\n")
+}
+
+func (p *printer) text(s string) {
+	if p.bol {
+		p.buf.WriteString(strings.Repeat(indent, p.indent))
+		p.bol = false
+	}
+	p.buf.WriteString(s)
+}
+
+func (p *printer) breakLine() {
+	if !p.bol {
+		p.buf.WriteString("\n")
+		p.bol = true
+	}
+}
+
+func (p *printer) start(line int) {
+	blank := !p.first && (line > p.lastLine+1 || p.force)
+	p.breakLine()
+	if blank {
+		p.buf.WriteString("\n")
+	}
+	p.first = false
+	p.force = false
+}
+
+func (p *printer) pending(offset int) bool {
+	return p.next < len(p.comments) && (offset < 0 || p.comments[p.next].Span.Start.Offset < offset)
+}
+
+func (p *printer) flush(offset int) {
+	for p.pending(offset) {
+		c := p.comments[p.next]
+		p.next++
+		text := strings.TrimRight(c.Content, " \t\r")
+		if !p.bol && c.Span.Start.Line == p.lastLine {
+			p.text(" " + text)
+		} else {
+			p.start(c.Span.Start.Line)
+			p.text(text)
+		}
+		p.lastLine = c.Span.Start.Line
+		p.breakLine()
+	}
+}
+
+func (p *printer) opts(opts []*parser.Ident) {
+	if len(opts) == 0 {
+		return
+	}
+	names := []string{}
+	for _, opt := range opts {
+		names = append(names, opt.Content)
+	}
+	p.text("{" + strings.Join(names, ", ") + "}")
+}
+
+func typ(t parser.Typ) string {
+	switch t := t.(type) {
+	case *parser.Custom:
+		return t.Ident
+	case parser.Builtin:
+		if t == parser.NEVER {
+			return "!"
+		}
+		return strings.ToLower(string(t))
+	default:
+		return ""
+	}
+}
+
+func typs(ts []parser.Typ) string {
+	names := []string{}
+	for _, t := range ts {
+		names = append(names, typ(t))
+	}
+	return strings.Join(names, ",")
+}
+
+var escapes = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+
+func quote(s string) string {
+	return "\"" + escapes.Replace(s) + "\""
+}
+
+func number(n *parser.Number) string {
+	sign, digits := "", n.Content
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	prefix := ""
+	switch n.Base {
+	case 16:
+		prefix = "0x"
+	case 2:
+		prefix = "0b"
+	}
+	suffix := ""
+	if n.Typ != nil {
+		suffix = typ(n.Typ)
+	}
+	return sign + prefix + digits + suffix
+}
+
+func call(c *parser.Call) string {
+	return c.Ident.Content + "(" + typs(c.Inputs) + ":" + typs(c.Outputs) + ")"
+}
+
+func (p *printer) imp(imp *parser.Import) {
+	p.text("import " + quote(imp.Path.Content))
+	if imp.Alias != nil {
+		p.text(" as " + imp.Alias.Content)
+	}
+	p.text(";")
+}
+
+func (p *printer) typList(ts []parser.Typ, spans []lexer.Span, end int) {
+	for i, t := range ts {
+		if i > 0 {
+			p.text(",")
+		}
+		p.flush(spans[i].Start.Offset)
+		p.text(typ(t))
+		p.lastLine = spans[i].End.Line
+	}
+	p.flush(end)
+}
+
+func (p *printer) typ(t *parser.Type) {
+	p.text("type")
+	p.opts(t.Opts)
+	p.text(" " + t.Ident.Content + "(")
+	p.lastLine = t.Sig.Start.Line
+	p.indent++
+	p.typList(t.Fields, t.FieldSpans, t.Sig.End.Offset)
+	p.indent--
+	p.text(");")
+}
+
+func (p *printer) let(l *parser.Let) {
+	p.text("let")
+	p.opts(l.Opts)
+	p.text(" " + l.Ident.Content + ": " + typ(l.Typ))
+	if len(l.Exprs) != 0 {
+		p.lastLine = l.Span.Start.Line
+		p.indent++
+		p.flush(leading(l.Exprs[0]))
+		if !p.bol {
+			p.text(" ")
+		}
+		p.inline(l.Exprs)
+		p.indent--
+	}
+	p.text(";")
+}
+
+func (p *printer) fun(f *parser.Fun) {
+	p.text("fun")
+	p.opts(f.Opts)
+	p.text(" " + f.Ident.Content + "(")
+	p.lastLine = f.Sig.Start.Line
+	p.indent++
+	p.typList(f.Inputs, f.InputSpans, f.Colon.Start.Offset)
+	p.text(":")
+	p.lastLine = f.Colon.Start.Line
+	p.typList(f.Outputs, f.OutputSpans, f.Sig.End.Offset)
+	p.indent--
+	p.text(") ")
+	p.lastLine = f.Block.Span.Start.Line
+	if len(f.Block.Lets) == 0 && len(f.Block.Exprs) == 0 && !p.pending(f.Block.Span.End.Offset) {
+		p.text("{}")
+		return
+	}
+	p.text("{")
+	p.indent++
+	p.first = true
+	for _, let := range f.Block.Lets {
+		p.flush(let.Span.Start.Offset)
+		p.start(let.Span.Start.Line)
+		p.let(let)
+		p.lastLine = let.Span.End.Line
+	}
+	p.exprs(f.Block.Exprs)
+	p.close(f.Block.Span.End.Offset, f.Block.Span.End.Line)
+}
+
+func (p *printer) close(offset int, line int) {
+	p.flush(offset)
+	p.indent--
+	p.breakLine()
+	p.text("}")
+	p.first = false
+	p.lastLine = line
+}
+
+func (p *printer) body(exprs []parser.Expr, span lexer.Span) {
+	if len(exprs) == 0 && !p.pending(span.End.Offset) {
+		p.text("{}")
+		p.lastLine = span.End.Line
+		return
+	}
+	p.text("{")
+	p.indent++
+	p.first = true
+	p.lastLine = span.Start.Line
+	p.exprs(exprs)
+	p.close(span.End.Offset, span.End.Line)
+}
+
+func leading(expr parser.Expr) int {
+	span := expr.GetSpan()
+	if expr.AsGroup() != nil || expr.AsIf() != nil || expr.AsWhile() != nil {
+		return span.Start.Offset
+	}
+	return span.End.Offset
+}
+
+func (p *printer) exprs(exprs []parser.Expr) {
+	cols := []int{}
+	for _, expr := range exprs {
+		span := expr.GetSpan()
+		p.flush(leading(expr))
+		if p.bol || p.first || span.Start.Line > p.lastLine {
+			p.start(span.Start.Line)
+			for len(cols) > 1 && cols[len(cols)-1] > span.Start.Col {
+				cols = cols[:len(cols)-1]
+			}
+			if len(cols) == 0 || cols[len(cols)-1] < span.Start.Col {
+				cols = append(cols, span.Start.Col)
+			}
+		} else {
+			p.text(" ")
+		}
+		hang := len(cols) - 1
+		p.indent += hang
+		p.expr(expr)
+		p.indent -= hang
+		p.lastLine = span.End.Line
+	}
+}
+
+func (p *printer) inline(exprs []parser.Expr) {
+	for i, expr := range exprs {
+		p.flush(leading(expr))
+		if i > 0 && !p.bol {
+			p.text(" ")
+		}
+		p.expr(expr)
+	}
+}
+
+func multiline(exprs []parser.Expr, line int) bool {
+	for _, expr := range exprs {
+		span := expr.GetSpan()
+		if span.Start.Line != line || span.End.Line != line || expr.AsIf() != nil || expr.AsWhile() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) group(open string, exprs []parser.Expr, line int, offset int, end int) {
+	p.text(open)
+	if !multiline(exprs, line) && !p.pending(offset) {
+		p.inline(exprs)
+		p.text(")")
+		p.lastLine = end
+		return
+	}
+	p.indent++
+	p.first = true
+	p.lastLine = line
+	p.exprs(exprs)
+	p.flush(offset)
+	p.indent--
+	p.breakLine()
+	p.text(")")
+	p.first = false
+	p.lastLine = end
+}
+
+func (p *printer) expr(expr parser.Expr) {
+	span := expr.GetSpan()
+	if ident := expr.AsIdent(); ident != nil {
+		p.text(ident.Content)
+	} else if c := expr.AsCall(); c != nil {
+		p.text(call(c))
+	} else if n := expr.AsNumber(); n != nil {
+		p.text(number(n))
+	} else if s := expr.AsString(); s != nil {
+		p.text(quote(s.Content))
+	} else if expr.AsUnwrap() != nil {
+		p.text(".unwrap")
+	} else if expr.AsReturn() != nil {
+		p.text(".return")
+	} else if expr.AsHole() != nil {
+		p.text("_")
+	} else if w := expr.AsWrap(); w != nil {
+		p.text(".wrap(" + typ(w.Typ) + ")")
+	} else if a := expr.AsAddr(); a != nil {
+		if a.Call != nil {
+			p.text(".addr(" + call(a.Call) + ")")
+		} else {
+			p.text(".addr(" + a.Ident.Content + ")")
+		}
+	} else if g := expr.AsGroup(); g != nil {
+		p.group("(", g.Exprs, span.Start.Line, span.End.Offset, span.End.Line)
+	} else if i := expr.AsIf(); i != nil {
+		p.group("if (", i.Con, span.Start.Line, i.ConSpan.End.Offset, i.ConSpan.End.Line)
+		p.text(" ")
+		p.body(i.Exprs, i.ExprsSpan)
+		if len(i.Else) != 0 || p.pending(i.ElseSpan.End.Offset) {
+			p.text(" else ")
+			p.body(i.Else, i.ElseSpan)
+		}
+	} else if w := expr.AsWhile(); w != nil {
+		p.group("while (", w.Con, span.Start.Line, w.ConSpan.End.Offset, w.ConSpan.End.Line)
+		p.text(" ")
+		p.body(w.Exprs, w.ExprsSpan)
+	}
+}
@@ -0,0 +1,134 @@
+package format
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var cases = []struct {
+	name string
+	src  string
+	want string
+}{
+	{
+		"type fields",
+		"type pair(\n    u64, // first\n    u64 // second\n);\n",
+		"type pair(u64, // first\n    u64 // second\n);\n",
+	},
+	{
+		"fun signature",
+		"fun foo(\n    u64, // a\n    u64 // b\n:u64) {}\n",
+		"fun foo(u64, // a\n    u64 // b\n    :u64) {}\n",
+	},
+	{
+		"fun outputs",
+		"fun foo(u64: // a\nu64) {}\n",
+		"fun foo(u64: // a\n    u64) {}\n",
+	},
+	{
+		"fun close",
+		"fun foo(u64:u64 // a\n) {}\n",
+		"fun foo(u64:u64 // a\n) {}\n",
+	},
+	{
+		"let",
+		"let a: u64 // c\n 1u64;\n",
+		"let a: u64 // c\n    1u64;\n",
+	},
+	{
+		"let own line",
+		"let a: u64\n// c\n1u64;\n",
+		"let a: u64\n    // c\n    1u64;\n",
+	},
+	{
+		"let between exprs",
+		"fun foo(:) {\n    let a: u64 1u64 // c\n    2u64 +(u64,u64:u64);\n}\n",
+		"fun foo(:) {\n    let a: u64 1u64 // c\n        2u64 +(u64,u64:u64);\n}\n",
+	},
+	{
+		"addr",
+		"fun foo(:) {\n    .addr( // c\n        a) drop(u64:)\n}\n",
+		"fun foo(:) {\n    // c\n    .addr(a) drop(u64:)\n}\n",
+	},
+	{
+		"call",
+		"fun foo(:) {\n    1u64 bar( // c\n        u64:) 2u64\n}\n",
+		"fun foo(:) {\n    1u64 // c\n        bar(u64:) 2u64\n}\n",
+	},
+	{
+		"group",
+		"fun foo(:) {\n    (a // c\n    b)\n}\n",
+		"fun foo(:) {\n    (\n        a // c\n        b\n    )\n}\n",
+	},
+	{
+		"empty if",
+		"fun foo(:) {\n    if (\n        x\n    ) {\n        // then\n    }\n}\n",
+		"fun foo(:) {\n    if (\n        x\n    ) {\n        // then\n    }\n}\n",
+	},
+	{
+		"empty else",
+		"fun foo(:) {\n    if (x) {} else { // else\n    }\n}\n",
+		"fun foo(:) {\n    if (x) {} else { // else\n    }\n}\n",
+	},
+	{
+		"empty while",
+		"fun foo(:) {\n    while (x) {\n        // body\n    }\n}\n",
+		"fun foo(:) {\n    while (x) {\n        // body\n    }\n}\n",
+	},
+	{
+		"condition",
+		"fun foo(:) {\n    if (\n        x // con\n    ) { y } else { z }\n}\n",
+		"fun foo(:) {\n    if (\n        x // con\n    ) {\n        y\n    } else {\n        z\n    }\n}\n",
+	},
+	{
+		"empty else dropped",
+		"fun foo(:) {\n    if (x) { y } else {}\n}\n",
+		"fun foo(:) {\n    if (x) {\n        y\n    }\n}\n",
+	},
+}
+
+func format(t *testing.T, file string, src string) string {
+	t.Helper()
+	out, diags := Source(file, src)
+	for _, d := range diags {
+		t.Error(d)
+	}
+	return out
+}
+
+func TestComments(t *testing.T) {
+	for _, c := range cases {
+		out := format(t, c.name, c.src)
+		if out != c.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", c.name, out, c.want)
+		}
+		if again := format(t, c.name, out); again != out {
+			t.Errorf("%s: not stable, got\n%s\nwant\n%s", c.name, again, out)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	files := []string{"../../fib.mvm", "../../test.mvm", "../../mvm/main.mvm"}
+	err := filepath.WalkDir("../core", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && strings.HasSuffix(path, ".mvm") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		dat, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out := format(t, file, string(dat)); out != string(dat) {
+			t.Errorf("'%s' changed when formatted", file)
+		}
+	}
+}
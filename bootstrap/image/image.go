@@ -0,0 +1,168 @@
+package image
+
+import (
+	"bootstrap/isa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	Magic   = "MyVM"
+	Version = 1
+
+	headerSize  = 24
+	sectionSize = 32
+	maxMemory   = 1 << 32
+)
+
+type SectionKind uint32
+
+const (
+	CODE      SectionKind = 1
+	DATA      SectionKind = 2
+	STRINGS   SectionKind = 3
+	SYMBOLS   SectionKind = 4
+	DEBUG     SectionKind = 5
+	BACKTRACE SectionKind = 6
+)
+
+func (k SectionKind) String() string {
+	switch k {
+	case CODE:
+		return "code"
+	case DATA:
+		return "data"
+	case STRINGS:
+		return "strings"
+	case SYMBOLS:
+		return "symbols"
+	case DEBUG:
+		return "debug"
+	case BACKTRACE:
+		return "backtrace"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint32(k))
+	}
+}
+
+func (k SectionKind) Loaded() bool {
+	return k == CODE || k == DATA || k == STRINGS || k == BACKTRACE
+}
+
+type Section struct {
+	Kind SectionKind
+	Addr uint64
+	Data []uint8
+}
+
+type Image struct {
+	Version  uint16
+	Isa      uint16
+	Entry    uint64
+	Sections []Section
+}
+
+var ErrNotImage = errors.New("missing 'MyVM' magic number")
+
+func IsImage(bytes []uint8) bool {
+	return len(bytes) >= len(Magic) && string(bytes[:len(Magic)]) == Magic
+}
+
+func (img *Image) Section(kind SectionKind) *Section {
+	for i := range img.Sections {
+		if img.Sections[i].Kind == kind {
+			return &img.Sections[i]
+		}
+	}
+	return nil
+}
+
+func (img *Image) Encode() []uint8 {
+	le := binary.LittleEndian
+	offset := uint64(headerSize + sectionSize*len(img.Sections))
+	bytes := make([]uint8, offset)
+	copy(bytes, Magic)
+	le.PutUint16(bytes[4:], img.Version)
+	le.PutUint16(bytes[6:], img.Isa)
+	le.PutUint64(bytes[8:], img.Entry)
+	le.PutUint32(bytes[16:], uint32(len(img.Sections)))
+	for i, s := range img.Sections {
+		head := bytes[headerSize+sectionSize*i:]
+		le.PutUint32(head, uint32(s.Kind))
+		le.PutUint64(head[8:], s.Addr)
+		le.PutUint64(head[16:], offset)
+		le.PutUint64(head[24:], uint64(len(s.Data)))
+		bytes = append(bytes, s.Data...)
+		offset += uint64(len(s.Data))
+	}
+	return bytes
+}
+
+func Decode(bytes []uint8) (*Image, error) {
+	le := binary.LittleEndian
+	if !IsImage(bytes) {
+		return nil, ErrNotImage
+	}
+	if len(bytes) < headerSize {
+		return nil, fmt.Errorf("truncated header")
+	}
+	img := &Image{Version: le.Uint16(bytes[4:]), Isa: le.Uint16(bytes[6:]), Entry: le.Uint64(bytes[8:])}
+	if img.Version != Version {
+		return nil, fmt.Errorf("unsupported format version %d, expected %d", img.Version, Version)
+	}
+	if img.Isa != isa.Version {
+		return nil, fmt.Errorf("unsupported isa version %d, expected %d", img.Isa, isa.Version)
+	}
+	count := uint64(le.Uint32(bytes[16:]))
+	if count > uint64(len(bytes)) || headerSize+sectionSize*count > uint64(len(bytes)) {
+		return nil, fmt.Errorf("truncated section table")
+	}
+	for i := uint64(0); i < count; i++ {
+		head := bytes[headerSize+sectionSize*i:]
+		kind := SectionKind(le.Uint32(head))
+		offset, size := le.Uint64(head[16:]), le.Uint64(head[24:])
+		if offset > uint64(len(bytes)) || size > uint64(len(bytes))-offset {
+			return nil, fmt.Errorf("the %s section is out of bounds", kind)
+		}
+		if img.Section(kind) != nil {
+			return nil, fmt.Errorf("duplicate %s section", kind)
+		}
+		img.Sections = append(img.Sections, Section{Kind: kind, Addr: le.Uint64(head[8:]), Data: bytes[offset : offset+size]})
+	}
+	if img.Section(CODE) == nil {
+		return nil, fmt.Errorf("missing code section")
+	}
+	return img, nil
+}
+
+func (img *Image) Memory() ([]uint8, error) {
+	var size uint64
+	for _, s := range img.Sections {
+		end := s.Addr + uint64(len(s.Data))
+		if s.Kind.Loaded() && (end < s.Addr || end > maxMemory) {
+			return nil, fmt.Errorf("the %s section doesn't fit into memory", s.Kind)
+		}
+		if s.Kind.Loaded() && end > size {
+			size = end
+		}
+	}
+	mem := make([]uint8, size)
+	used := make([]bool, size)
+	for _, s := range img.Sections {
+		if !s.Kind.Loaded() {
+			continue
+		}
+		for i := range s.Data {
+			if used[s.Addr+uint64(i)] {
+				return nil, fmt.Errorf("the %s section overlaps another section", s.Kind)
+			}
+			used[s.Addr+uint64(i)] = true
+		}
+		copy(mem[s.Addr:], s.Data)
+	}
+	if img.Entry >= size {
+		return nil, fmt.Errorf("the entry point 0x%x is outside of the loaded sections", img.Entry)
+	}
+	return mem, nil
+}
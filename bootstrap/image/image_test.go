@@ -0,0 +1,107 @@
+package image
+
+import (
+	"bootstrap/isa"
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the image fixtures shared with the rust vm")
+
+func image(entry uint64, sections ...Section) *Image {
+	return &Image{Version: Version, Isa: isa.Version, Entry: entry, Sections: sections}
+}
+
+var fixtures = []struct {
+	name string
+	img  *Image
+	mem  []uint8
+	err  string
+}{
+	{
+		"valid",
+		image(2,
+			Section{Kind: CODE, Addr: 0, Data: []uint8{0, 0, 1}},
+			Section{Kind: DATA, Addr: 3, Data: []uint8{7}},
+			Section{Kind: STRINGS, Addr: 4, Data: []uint8("hi")},
+			Section{Kind: SYMBOLS, Data: EncodeSymbols([]Symbol{{Kind: "fun", Name: ".entry", Addr: 0, Size: 3}})},
+		),
+		[]uint8{0, 0, 1, 7, 'h', 'i'},
+		"",
+	},
+	{
+		"overlap",
+		image(0,
+			Section{Kind: CODE, Addr: 0, Data: []uint8{1, 2, 3}},
+			Section{Kind: DATA, Addr: 2, Data: []uint8{4}},
+		),
+		nil,
+		"the data section overlaps another section",
+	},
+	{
+		"entry",
+		image(3, Section{Kind: CODE, Addr: 0, Data: []uint8{1, 2, 3}}),
+		nil,
+		"the entry point 0x3 is outside of the loaded sections",
+	},
+}
+
+func TestFixtures(t *testing.T) {
+	for _, fixture := range fixtures {
+		path := filepath.Join("..", "..", "vm", "testdata", fixture.name+".img")
+		data := fixture.img.Encode()
+		if *update {
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if file, err := os.ReadFile(path); err != nil || !bytes.Equal(file, data) {
+			t.Errorf("'%s' is out of date, run go test ./image -update", path)
+		}
+
+		img, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: %s", fixture.name, err)
+			continue
+		}
+		mem, err := img.Memory()
+		if fixture.err != "" {
+			if err == nil || err.Error() != fixture.err {
+				t.Errorf("%s: got error %v, want '%s'", fixture.name, err, fixture.err)
+			}
+		} else if err != nil || !bytes.Equal(mem, fixture.mem) || img.Entry != fixture.img.Entry {
+			t.Errorf("%s: got % x at 0x%x (%v), want % x at 0x%x", fixture.name, mem, img.Entry, err, fixture.mem, fixture.img.Entry)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := fixtures[0].img.Encode()
+	patch := func(at int, b ...uint8) []uint8 {
+		data := append([]uint8{}, valid...)
+		copy(data[at:], b)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []uint8
+		err  string
+	}{
+		{"magic", []uint8("NoVM"), "missing 'MyVM' magic number"},
+		{"header", []uint8("MyVM\x01\x00"), "truncated header"},
+		{"version", patch(4, 9), "unsupported format version 9, expected 1"},
+		{"isa", patch(6, 0xff), "unsupported isa version 255, expected 2"},
+		{"sections", patch(16, 0xff), "truncated section table"},
+		{"bounds", patch(headerSize+24, 0xff), "the code section is out of bounds"},
+		{"duplicate", patch(headerSize+sectionSize, uint8(CODE)), "duplicate code section"},
+		{"code", image(0).Encode(), "missing code section"},
+	}
+	for _, test := range tests {
+		if _, err := Decode(test.data); err == nil || err.Error() != test.err {
+			t.Errorf("%s: got error %v, want '%s'", test.name, err, test.err)
+		}
+	}
+}
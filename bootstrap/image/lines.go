@@ -0,0 +1,89 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+type Line struct {
+	Addr uint64
+	Size uint64
+	File string
+	Line uint32
+	Col  uint32
+	Fun  string
+}
+
+const lineSize = 32
+
+func EncodeLines(lines []Line) []uint8 {
+	le := binary.LittleEndian
+	strs := []string{}
+	index := make(map[string]uint32)
+	intern := func(s string) uint32 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = uint32(len(strs))
+		strs = append(strs, s)
+		return index[s]
+	}
+	entries := make([]uint8, 4, 4+lineSize*len(lines))
+	le.PutUint32(entries, uint32(len(lines)))
+	for _, line := range lines {
+		buf := make([]uint8, lineSize)
+		le.PutUint64(buf, line.Addr)
+		le.PutUint64(buf[8:], line.Size)
+		le.PutUint32(buf[16:], intern(line.File))
+		le.PutUint32(buf[20:], intern(line.Fun))
+		le.PutUint32(buf[24:], line.Line)
+		le.PutUint32(buf[28:], line.Col)
+		entries = append(entries, buf...)
+	}
+	bytes := make([]uint8, 4)
+	le.PutUint32(bytes, uint32(len(strs)))
+	for _, s := range strs {
+		buf := make([]uint8, 4)
+		le.PutUint32(buf, uint32(len(s)))
+		bytes = append(bytes, buf...)
+		bytes = append(bytes, s...)
+	}
+	return append(bytes, entries...)
+}
+
+func DecodeLines(bytes []uint8) ([]Line, error) {
+	le := binary.LittleEndian
+	if len(bytes) < 4 {
+		return nil, fmt.Errorf("truncated debug info")
+	}
+	count := le.Uint32(bytes)
+	bytes = bytes[4:]
+	strs := []string{}
+	for i := uint32(0); i < count; i++ {
+		if len(bytes) < 4 || uint64(len(bytes)-4) < uint64(le.Uint32(bytes)) {
+			return nil, fmt.Errorf("truncated debug info")
+		}
+		n := le.Uint32(bytes)
+		strs = append(strs, string(bytes[4:4+n]))
+		bytes = bytes[4+n:]
+	}
+	if len(bytes) < 4 || uint64(len(bytes)-4) != uint64(le.Uint32(bytes))*lineSize {
+		return nil, fmt.Errorf("truncated debug info")
+	}
+	lines := []Line{}
+	for bytes = bytes[4:]; len(bytes) != 0; bytes = bytes[lineSize:] {
+		file, fun := le.Uint32(bytes[16:]), le.Uint32(bytes[20:])
+		if file >= uint32(len(strs)) || fun >= uint32(len(strs)) {
+			return nil, fmt.Errorf("invalid string index in debug info")
+		}
+		lines = append(lines, Line{
+			Addr: le.Uint64(bytes),
+			Size: le.Uint64(bytes[8:]),
+			File: strs[file],
+			Fun:  strs[fun],
+			Line: le.Uint32(bytes[24:]),
+			Col:  le.Uint32(bytes[28:]),
+		})
+	}
+	return lines, nil
+}
@@ -0,0 +1,55 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+type Symbol struct {
+	Kind string
+	Name string
+	Addr uint64
+	Size uint64
+}
+
+func EncodeSymbols(syms []Symbol) []uint8 {
+	le := binary.LittleEndian
+	bytes := []uint8{}
+	for _, sym := range syms {
+		buf := make([]uint8, 16)
+		le.PutUint64(buf, sym.Addr)
+		le.PutUint64(buf[8:], sym.Size)
+		bytes = append(bytes, buf...)
+		bytes = append(bytes, uint8(len(sym.Kind)))
+		bytes = append(bytes, sym.Kind...)
+		bytes = append(bytes, uint8(len(sym.Name)), uint8(len(sym.Name)>>8))
+		bytes = append(bytes, sym.Name...)
+	}
+	return bytes
+}
+
+func DecodeSymbols(bytes []uint8) ([]Symbol, error) {
+	le := binary.LittleEndian
+	syms := []Symbol{}
+	for len(bytes) != 0 {
+		if len(bytes) < 17 {
+			return nil, fmt.Errorf("truncated symbol")
+		}
+		sym := Symbol{Addr: le.Uint64(bytes), Size: le.Uint64(bytes[8:])}
+		n := int(bytes[16])
+		bytes = bytes[17:]
+		if len(bytes) < n+2 {
+			return nil, fmt.Errorf("truncated symbol")
+		}
+		sym.Kind = string(bytes[:n])
+		m := int(le.Uint16(bytes[n:]))
+		bytes = bytes[n+2:]
+		if len(bytes) < m {
+			return nil, fmt.Errorf("truncated symbol")
+		}
+		sym.Name = string(bytes[:m])
+		bytes = bytes[m:]
+		syms = append(syms, sym)
+	}
+	return syms, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bootstrap/isa"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+)
+
+func goTyps(typs []string) string {
+	res := []string{}
+	for _, typ := range typs {
+		if typ == "!" {
+			typ = "never"
+		}
+		res = append(res, "parser."+strings.ToUpper(typ))
+	}
+	return strings.Join(res, ", ")
+}
+
+const header = "Code generated by isa/gen from isa/isa.txt; DO NOT EDIT."
+
+func goTables(insts []isa.Inst) ([]uint8, error) {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// %s\n\n", header)
+	fmt.Fprintf(b, "package compiler\n\nimport \"bootstrap/parser\"\n\n")
+	fmt.Fprintf(b, "var insts = map[string]uint8{\n")
+	for _, inst := range insts {
+		if inst.Imm == 0 {
+			fmt.Fprintf(b, "\t%q: %d,\n", inst.Name, inst.Code)
+		}
+	}
+	fmt.Fprintf(b, "}\n\nvar immInsts = map[string]imm{\n")
+	for _, inst := range insts {
+		if inst.Imm != 0 {
+			fmt.Fprintf(b, "\t%q: {%d, %d},\n", inst.Name, inst.Code, inst.Imm)
+		}
+	}
+	fmt.Fprintf(b, "}\n\nfunc argsInst(inst string) ([]parser.Typ, []parser.Typ, bool) {\n\tswitch inst {\n")
+	for _, inst := range insts {
+		fmt.Fprintf(b, "\t// %03d\n\tcase %q:\n\t\treturn args(%s), args(%s), true\n", inst.Code, inst.Name, goTyps(inst.Inputs), goTyps(inst.Outputs))
+	}
+	fmt.Fprintf(b, "\tdefault:\n\t\treturn nil, nil, false\n\t}\n}\n")
+	return format.Source(b.Bytes())
+}
+
+type group struct {
+	start int
+	insts []*isa.Inst
+}
+
+func groups(insts []isa.Inst) []group {
+	byCode := [256]*isa.Inst{}
+	for i := range insts {
+		byCode[insts[i].Code] = &insts[i]
+	}
+	res := []group{}
+	for start := 0; start < 256; start += 10 {
+		end := start + 10
+		if end > 256 {
+			end = 256
+		}
+		res = append(res, group{start: start, insts: byCode[start:end]})
+	}
+	return res
+}
+
+func holes(g group, i int) int {
+	n := 0
+	for i+n < len(g.insts) && g.insts[i+n] == nil {
+		n++
+	}
+	return n
+}
+
+func markdown(insts []isa.Inst) []uint8 {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<!-- %s -->\n\n# Inst\n", header)
+	for _, g := range groups(insts) {
+		width := 0
+		for _, inst := range g.insts {
+			if inst != nil && len(inst.Name) > width {
+				width = len(inst.Name)
+			}
+		}
+		if width == 0 {
+			continue
+		}
+		if b.Len() != 0 && !bytes.HasSuffix(b.Bytes(), []uint8("\n\n")) {
+			fmt.Fprintf(b, "\n")
+		}
+		for i := 0; i < len(g.insts); i++ {
+			inst := g.insts[i]
+			if n := holes(g, i); n > 2 {
+				fmt.Fprintf(b, "- %03d-%03d: --\n", g.start+i, g.start+i+n-1)
+				i += n - 1
+			} else if inst == nil {
+				fmt.Fprintf(b, "- %03d: --\n", g.start+i)
+			} else {
+				fmt.Fprintf(b, "- %03d: %-*s %s\n", inst.Code, width+2, "`"+inst.Name+"`", inst.Effect())
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+var suffixes = []string{"_u8", "_u16", "_u32", "_u64", "_u128", "_i8", "_i16", "_i32", "_i64", "_i128"}
+
+func asmName(name string) string {
+	if strings.Contains(name, "_to_") {
+		return "to"
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	return strings.ReplaceAll(name, "_", ".")
+}
+
+func asmMvm(insts []isa.Inst) []uint8 {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// %s\n\n", header)
+	for _, g := range groups(insts) {
+		wrapped := false
+		for _, inst := range g.insts {
+			wrapped = wrapped || (inst != nil && inst.Imm == 0)
+		}
+		if !wrapped {
+			continue
+		}
+		if b.Len() != 0 && !bytes.HasSuffix(b.Bytes(), []uint8("\n\n")) {
+			fmt.Fprintf(b, "\n")
+		}
+		for i := 0; i < len(g.insts); i++ {
+			inst := g.insts[i]
+			if n := holes(g, i); n > 2 {
+				fmt.Fprintf(b, "// %03d-%03d\n", g.start+i, g.start+i+n-1)
+				i += n - 1
+			} else if inst == nil {
+				fmt.Fprintf(b, "// %03d\n", g.start+i)
+			} else if inst.Imm == 0 {
+				fmt.Fprintf(b, "// %03d\nfun{unsafe, inline, asm} .asm.%s%s {\n    %q\n}\n\n", inst.Code, asmName(inst.Name), inst.Effect(), inst.Name)
+			}
+		}
+	}
+	return append(bytes.TrimRight(b.Bytes(), "\n"), '\n')
+}
+
+type file struct {
+	path string
+	data []uint8
+}
+
+func generate() ([]file, error) {
+	insts, err := isa.Parse(isa.Source)
+	if err != nil {
+		return nil, fmt.Errorf("isa.txt: %s", err)
+	}
+	tables, err := goTables(insts)
+	if err != nil {
+		return nil, err
+	}
+	return []file{
+		{"../compiler/isa.go", tables},
+		{"../../vm/Inst.md", markdown(insts)},
+		{"../core/asm.mvm", asmMvm(insts)},
+	}, nil
+}
+
+func main() {
+	check := flag.Bool("check", false, "only check that the generated files are up to date")
+	flag.Parse()
+
+	files, err := generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	stale := false
+	for _, file := range files {
+		if *check {
+			data, err := os.ReadFile(file.path)
+			if err != nil || !bytes.Equal(data, file.data) {
+				fmt.Fprintf(os.Stderr, "error: '%s' is out of date with isa.txt, run go generate\n", file.path)
+				stale = true
+			}
+		} else if err := os.WriteFile(file.path, file.data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerated(t *testing.T) {
+	files, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join("..", file.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, file.data) {
+			t.Errorf("'%s' is out of date with isa.txt, run go generate ./isa", file.path)
+		}
+		if !bytes.Contains(bytes.SplitN(data, []uint8("\n"), 2)[0], []uint8(header)) {
+			t.Errorf("'%s' doesn't start with the generated code header", file.path)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package isa
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run ./gen
+
+const Version = 2
+
+//go:embed isa.txt
+var Source string
+
+type Inst struct {
+	Code    uint8
+	Name    string
+	Imm     int
+	Inputs  []string
+	Outputs []string
+}
+
+func (i Inst) Effect() string {
+	return "(" + strings.Join(i.Inputs, ",") + ":" + strings.Join(i.Outputs, ",") + ")"
+}
+
+var typs = map[string]bool{
+	"u8": true, "u16": true, "u32": true, "u64": true, "u128": true,
+	"i8": true, "i16": true, "i32": true, "i64": true, "i128": true,
+	"bool": true, "string": true, "!": true,
+}
+
+func parseTyps(text string) ([]string, error) {
+	res := []string{}
+	if text == "" {
+		return res, nil
+	}
+	for _, typ := range strings.Split(text, ",") {
+		if !typs[typ] {
+			return nil, fmt.Errorf("unknown type '%s'", typ)
+		}
+		res = append(res, typ)
+	}
+	return res, nil
+}
+
+func parseLine(line string) (Inst, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 && len(fields) != 4 {
+		return Inst{}, fmt.Errorf("expected 'code name (inputs:outputs) [imm]'")
+	}
+	code, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return Inst{}, fmt.Errorf("invalid opcode '%s'", fields[0])
+	}
+	inst := Inst{Code: uint8(code), Name: fields[1]}
+	effect := fields[2]
+	colon := strings.Index(effect, ":")
+	if !strings.HasPrefix(effect, "(") || !strings.HasSuffix(effect, ")") || colon < 0 {
+		return Inst{}, fmt.Errorf("invalid stack effect '%s'", effect)
+	}
+	if inst.Inputs, err = parseTyps(effect[1:colon]); err != nil {
+		return Inst{}, err
+	}
+	if inst.Outputs, err = parseTyps(effect[colon+1 : len(effect)-1]); err != nil {
+		return Inst{}, err
+	}
+	if len(fields) == 4 {
+		imm, err := strconv.Atoi(fields[3])
+		if err != nil || (imm != 1 && imm != 2 && imm != 4 && imm != 8 && imm != 16) {
+			return Inst{}, fmt.Errorf("invalid immediate size '%s'", fields[3])
+		}
+		inst.Imm = imm
+	}
+	return inst, nil
+}
+
+func Parse(src string) ([]Inst, error) {
+	res := []Inst{}
+	codes := make(map[uint8]string)
+	names := make(map[string]bool)
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inst, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		if name, ok := codes[inst.Code]; ok {
+			return nil, fmt.Errorf("line %d: the opcode %03d is already used by '%s'", i+1, inst.Code, name)
+		}
+		if names[inst.Name] {
+			return nil, fmt.Errorf("line %d: the instruction '%s' already exists", i+1, inst.Name)
+		}
+		codes[inst.Code] = inst.Name
+		names[inst.Name] = true
+		res = append(res, inst)
+	}
+	return res, nil
+}
+
+func Insts() []Inst {
+	insts, err := Parse(Source)
+	if err != nil {
+		panic(err)
+	}
+	return insts
+}
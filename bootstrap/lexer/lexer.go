@@ -17,13 +17,42 @@ var num = [...]string{
 }
 
 type Lexer struct {
-	cursor int
-	input  string
-	peeked *Token
+	file     string
+	cursor   int
+	input    string
+	peeked   *Token
+	offset   int
+	line     int
+	col      int
+	comments []Token
 }
 
-func New(input string) *Lexer {
-	return &Lexer{input: input}
+func New(file string, input string) *Lexer {
+	return &Lexer{file: file, input: input, line: 1, col: 1}
+}
+
+func (l *Lexer) File() string {
+	return l.file
+}
+
+func (l *Lexer) Comments() []Token {
+	return l.comments
+}
+
+func (l *Lexer) pos(offset int) Pos {
+	for ; l.offset < offset; l.offset++ {
+		if l.input[l.offset] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+	}
+	return Pos{Offset: offset, Line: l.line, Col: l.col}
+}
+
+func (l *Lexer) span(start int, end int) Span {
+	return Span{File: l.file, Start: l.pos(start), End: l.pos(end)}
 }
 
 func find(s string) int {
@@ -44,6 +73,7 @@ func find(s string) int {
 }
 
 func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
 	for i := 0; i < len(num); i++ {
 		if strings.HasPrefix(s, num[i]) {
 			return true
@@ -60,18 +90,20 @@ func (l *Lexer) skipComment() {
 	}
 }
 
-func (l *Lexer) string() string {
+func (l *Lexer) string() (string, string) {
 	var buf string
+	var err string
 	start := l.cursor
 	for ; l.cursor < len(l.input); l.cursor++ {
 		char := l.input[l.cursor]
 		switch char {
 		case '"':
 			l.cursor++
-			return buf + l.input[start:l.cursor-1]
+			return buf + l.input[start:l.cursor-1], err
 		case '\\':
 			if l.cursor+2 >= len(l.input) {
-				panic("no end of string")
+				l.cursor = len(l.input)
+				return buf, "no end of string"
 			}
 			buf += l.input[start:l.cursor]
 			l.cursor++
@@ -89,12 +121,14 @@ func (l *Lexer) string() string {
 			case '"':
 				buf += "\""
 			default:
-				panic(fmt.Sprintf("can't escape '%s' in a string", string(char)))
+				if err == "" {
+					err = fmt.Sprintf("can't escape '%s' in a string", string(escaped))
+				}
 			}
 		default:
 		}
 	}
-	panic("no end of string")
+	return buf, "no end of string"
 }
 
 func (l *Lexer) ConsumePeek() {
@@ -109,9 +143,10 @@ func (l *Lexer) RawNext() Token {
 	}
 
 	if l.cursor >= len(l.input) {
-		return Token{Typ: EOF}
+		return Token{Typ: EOF, Span: l.span(l.cursor, l.cursor)}
 	}
 
+	start := l.cursor
 	var content = l.input[l.cursor:]
 	pos := find(content)
 
@@ -142,11 +177,18 @@ func (l *Lexer) RawNext() Token {
 	case "}":
 		token.Typ = RBRACE
 	case "//":
-		token.Typ = IGNORED
+		token.Typ = COMMENT
 		l.skipComment()
+		token.Content = l.input[start:l.cursor]
 	case "\"":
-		token.Typ = STRING
-		token.Content = l.string()
+		str, err := l.string()
+		if err != "" {
+			token.Typ = ILLEGAL
+			token.Content = err
+		} else {
+			token.Typ = STRING
+			token.Content = str
+		}
 	case "fun":
 		token.Typ = FUN
 	case "let":
@@ -169,6 +211,8 @@ func (l *Lexer) RawNext() Token {
 		token.Typ = RETURN
 	case "while":
 		token.Typ = WHILE
+	case "_", "?":
+		token.Typ = HOLE
 	default:
 		if isNumber(content) {
 			token.Typ = NUMBER
@@ -177,6 +221,10 @@ func (l *Lexer) RawNext() Token {
 		}
 	}
 
+	token.Span = l.span(start, l.cursor)
+	if token.Typ == COMMENT {
+		l.comments = append(l.comments, token)
+	}
 	return token
 }
 
@@ -190,14 +238,14 @@ func (l *Lexer) RawPeek() *Token {
 
 func (l *Lexer) Next() Token {
 	var token Token
-	for token = l.RawNext(); token.Typ == IGNORED; token = l.RawNext() {
+	for token = l.RawNext(); token.Typ == IGNORED || token.Typ == COMMENT; token = l.RawNext() {
 	}
 	return token
 }
 
 func (l *Lexer) Peek() *Token {
 	var token *Token
-	for token = l.RawPeek(); token.Typ == IGNORED; token = l.RawPeek() {
+	for token = l.RawPeek(); token.Typ == IGNORED || token.Typ == COMMENT; token = l.RawPeek() {
 		l.ConsumePeek()
 	}
 	return token
@@ -1,10 +1,13 @@
 package lexer
 
+import "fmt"
+
 type Typ string
 
 const (
 	EOF     Typ = "EOF"
 	IGNORED Typ = "IGNORED"
+	ILLEGAL Typ = "ILLEGAL"
 
 	COLON     Typ = ":"
 	SEMICOLON Typ = ";"
@@ -30,6 +33,7 @@ const (
 	ADDR   Typ = "ADDR"
 	RETURN Typ = "RETURN"
 	WHILE  Typ = "WHILE"
+	HOLE   Typ = "HOLE"
 
 	IDENT Typ = "IDENT"
 )
@@ -37,4 +41,28 @@ const (
 type Token struct {
 	Typ     Typ
 	Content string
+	Span    Span
+}
+
+type Pos struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+type Span struct {
+	File  string
+	Start Pos
+	End   Pos
+}
+
+func (s Span) To(end Span) Span {
+	return Span{File: s.File, Start: s.Start, End: end.End}
+}
+
+func (s Span) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("%d:%d", s.Start.Line, s.Start.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", s.File, s.Start.Line, s.Start.Col)
 }
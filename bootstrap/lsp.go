@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bootstrap/lsp"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func lspCmd(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("lsp", &includes, &isaFile)
+	set.Parse(args)
+	if set.NArg() != 0 {
+		usageError(set, "unexpected arguments")
+	}
+	dirs := []string{}
+	for _, dir := range searchPaths(includes) {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid search path '%s'\n", dir)
+			os.Exit(1)
+		}
+		dirs = append(dirs, filepath.ToSlash(abs))
+	}
+	if err := lsp.New(os.Stdin, os.Stdout, dirs, builtin, loadIsa(isaFile)).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
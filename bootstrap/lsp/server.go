@@ -0,0 +1,369 @@
+package lsp
+
+import (
+	"bootstrap/compiler"
+	"bootstrap/diag"
+	"bootstrap/isa"
+	"bootstrap/lexer"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Server struct {
+	in          *bufio.Reader
+	out         io.Writer
+	searchPaths []string
+	builtin     fs.FS
+	insts       []isa.Inst
+	docs        map[string]string
+	analyses    map[string]*compiler.Analysis
+	shutdown    bool
+	builtinDir  string
+}
+
+func New(in io.Reader, out io.Writer, searchPaths []string, builtin fs.FS, insts []isa.Inst) *Server {
+	return &Server{
+		in:          bufio.NewReader(in),
+		out:         out,
+		searchPaths: searchPaths,
+		builtin:     builtin,
+		insts:       insts,
+		docs:        make(map[string]string),
+		analyses:    make(map[string]*compiler.Analysis),
+	}
+}
+
+type overlay struct {
+	docs map[string]string
+}
+
+type docInfo struct {
+	name string
+	size int
+}
+
+func (i docInfo) Name() string       { return i.name }
+func (i docInfo) Size() int64        { return int64(i.size) }
+func (i docInfo) Mode() fs.FileMode  { return 0444 }
+func (i docInfo) ModTime() time.Time { return time.Time{} }
+func (i docInfo) IsDir() bool        { return false }
+func (i docInfo) Sys() interface{}   { return nil }
+
+func (o overlay) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (o overlay) ReadFile(name string) ([]uint8, error) {
+	if text, ok := o.docs[name]; ok {
+		return []uint8(text), nil
+	}
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+func (o overlay) Stat(name string) (fs.FileInfo, error) {
+	if text, ok := o.docs[name]; ok {
+		return docInfo{name: filepath.Base(name), size: len(text)}, nil
+	}
+	return os.Stat(filepath.FromSlash(name))
+}
+
+func (s *Server) read() (*request, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid content length '%s'", strings.TrimSpace(value))
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing content length")
+	}
+	body := make([]uint8, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	req := &request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("invalid message: %s", err)
+	}
+	return req, nil
+}
+
+func (s *Server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(notification{Jsonrpc: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) Run() error {
+	defer func() {
+		if s.builtinDir != "" {
+			os.RemoveAll(s.builtinDir)
+		}
+	}()
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of input")
+		}
+		if err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+		result, rerr := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		if rerr != nil {
+			err = s.write(errorResponse{Jsonrpc: "2.0", ID: *req.ID, Error: *rerr})
+		} else {
+			err = s.write(response{Jsonrpc: "2.0", ID: *req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, *respError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1,
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "mvm"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		params := didOpenParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &respError{Code: invalidParams, Message: err.Error()}
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		params := didChangeParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &respError{Code: invalidParams, Message: err.Error()}
+		}
+		if n := len(params.ContentChanges); n != 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didClose":
+		params := didOpenParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &respError{Code: invalidParams, Message: err.Error()}
+		}
+		path := uriPath(params.TextDocument.URI)
+		delete(s.docs, path)
+		delete(s.analyses, path)
+		s.notify("textDocument/publishDiagnostics", publishParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}})
+	case "textDocument/hover", "textDocument/definition", "textDocument/completion", "mvm/typeStack":
+		params := positionParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &respError{Code: invalidParams, Message: err.Error()}
+		}
+		path := uriPath(params.TextDocument.URI)
+		text, ok := s.docs[path]
+		a := s.analyses[path]
+		if !ok || a == nil {
+			return nil, nil
+		}
+		offset := offsetOf(text, params.Position)
+		switch req.Method {
+		case "textDocument/hover":
+			return s.hover(a, path, text, offset), nil
+		case "textDocument/definition":
+			return s.definition(a, path, offset), nil
+		case "textDocument/completion":
+			return s.complete(a, path, text, offset), nil
+		default:
+			return s.typeStack(a, path, text, offset), nil
+		}
+	default:
+		if req.ID != nil {
+			return nil, &respError{Code: methodNotFound, Message: fmt.Sprintf("unknown method '%s'", req.Method)}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) update(uri string, text string) {
+	path := uriPath(uri)
+	s.docs[path] = text
+	a := compiler.Analyze(context.Background(), compiler.Options{
+		Entry:       path,
+		SearchPaths: s.searchPaths,
+		FS:          overlay{docs: s.docs},
+		Builtin:     s.builtin,
+		Insts:       s.insts,
+	})
+	if a.Resolved() || s.analyses[path] == nil {
+		s.analyses[path] = a
+	}
+	s.notify("textDocument/publishDiagnostics", publishParams{URI: uri, Diagnostics: s.diagnostics(path, text, a.Diags)})
+}
+
+func (s *Server) diagnostics(path string, text string, diags []diag.Diagnostic) []diagnostic {
+	res := []diagnostic{}
+	for _, d := range diags {
+		msg := d.Msg
+		r := textRange{}
+		if d.Span.File == path {
+			r = rangeOf(text, d.Span)
+		} else if d.Span != (lexer.Span{}) {
+			msg = d.Span.String() + ": " + msg
+		}
+		for _, note := range d.Notes {
+			msg += "\n" + string(diag.NOTE) + ": "
+			if note.Span != (lexer.Span{}) {
+				msg += note.Span.String() + ": "
+			}
+			msg += note.Msg
+		}
+		res = append(res, diagnostic{Range: r, Severity: severities[d.Severity], Source: "mvm", Message: msg})
+	}
+	return res
+}
+
+func (s *Server) text(path string) string {
+	if text, ok := s.docs[path]; ok {
+		return text
+	}
+	dat, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return ""
+	}
+	return string(dat)
+}
+
+func (s *Server) hover(a *compiler.Analysis, path string, text string, offset int) interface{} {
+	lines := []string{}
+	var r *textRange
+	if ref := a.Lookup(path, offset); ref != nil {
+		lines = append(lines, "```mvm\n"+ref.Sig+"\n```")
+		if ref.Doc != "" {
+			lines = append(lines, ref.Doc)
+		}
+		span := rangeOf(text, ref.Span)
+		r = &span
+	}
+	if stack := a.StackAt(path, offset); stack != nil {
+		lines = append(lines, "stack: `"+compiler.FormatStack(stack.After)+"`")
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return hover{Contents: markup{Kind: "markdown", Value: strings.Join(lines, "\n\n")}, Range: r}
+}
+
+func (s *Server) builtinFile(file string) (string, error) {
+	name := strings.TrimPrefix(file, compiler.BuiltinDir+"/")
+	if s.builtinDir == "" {
+		dir, err := os.MkdirTemp("", "mvm-builtin-")
+		if err != nil {
+			return "", err
+		}
+		s.builtinDir = dir
+	}
+	path := filepath.Join(s.builtinDir, filepath.FromSlash(name))
+	if _, err := os.Stat(path); err == nil {
+		return filepath.ToSlash(path), nil
+	}
+	dat, err := fs.ReadFile(s.builtin, name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, dat, 0444); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(path), nil
+}
+
+func (s *Server) definition(a *compiler.Analysis, path string, offset int) interface{} {
+	ref := a.Lookup(path, offset)
+	if ref == nil || ref.Decl.File == "" {
+		return nil
+	}
+	file := ref.Decl.File
+	if strings.HasPrefix(file, compiler.BuiltinDir+"/") {
+		var err error
+		if file, err = s.builtinFile(file); err != nil {
+			return nil
+		}
+	}
+	return location{URI: pathURI(file), Range: rangeOf(s.text(file), ref.Decl)}
+}
+
+func isDelim(c uint8) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || strings.IndexByte("(){};\"", c) >= 0
+}
+
+func (s *Server) complete(a *compiler.Analysis, path string, text string, offset int) interface{} {
+	start := offset
+	for start > 0 && !isDelim(text[start-1]) {
+		start--
+	}
+	prefix := text[start:offset]
+	r := textRange{Start: positionOf(text, start), End: positionOf(text, offset)}
+	items := []completionItem{}
+	for _, ref := range a.Complete(path, offset, prefix) {
+		item := completionItem{Label: ref.Text, Kind: 3, Detail: ref.Sig, FilterText: ref.Name}
+		item.TextEdit = textEdit{Range: r, NewText: ref.Text}
+		if ref.Kind == compiler.LET {
+			item.Kind = 6
+		}
+		if ref.Doc != "" {
+			item.Documentation = &markup{Kind: "markdown", Value: ref.Doc}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (s *Server) typeStack(a *compiler.Analysis, path string, text string, offset int) interface{} {
+	stack := a.StackAt(path, offset)
+	if stack == nil {
+		return nil
+	}
+	return typeStack{Fun: stack.Fun, Range: rangeOf(text, stack.Span), Before: stack.Before, After: stack.After}
+}
@@ -0,0 +1,201 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const src = `import "core/prelude.mvm";
+
+fun{safe} greet(:) {
+    "hi\n" print(string:)
+}
+
+fun{safe} main(:) {
+    greet(:)
+}
+`
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *respError      `json:"error"`
+}
+
+type client struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	out   *bufio.Reader
+	id    int
+	diags map[string][]diagnostic
+	done  chan error
+}
+
+func start(t *testing.T) *client {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), diags: map[string][]diagnostic{}, done: make(chan error, 1)}
+	go func() {
+		err := New(inR, outW, nil, os.DirFS(".."), nil).Run()
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) send(id *int, method string, params interface{}) {
+	c.t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) receive() *message {
+	c.t.Helper()
+	length := 0
+	for {
+		line, err := c.out.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		fmt.Sscanf(line, "Content-Length: %d", &length)
+	}
+	body := make([]uint8, length)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatal(err)
+	}
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.Method == "textDocument/publishDiagnostics" {
+		params := publishParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		c.diags[params.URI] = params.Diagnostics
+	}
+	return msg
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(nil, method, params)
+	if strings.HasPrefix(method, "textDocument/did") {
+		c.receive()
+	}
+}
+
+func (c *client) call(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+	c.id++
+	id := c.id
+	c.send(&id, method, params)
+	for {
+		msg := c.receive()
+		if msg.ID == nil || *msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("%s: %s", method, msg.Error.Message)
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+func (c *client) stop() {
+	c.t.Helper()
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func at(uri string, line int, char int) positionParams {
+	return positionParams{TextDocument: textDocument{URI: uri}, Position: position{Line: line, Character: char}}
+}
+
+func TestServer(t *testing.T) {
+	uri := pathURI(filepath.ToSlash(filepath.Join(t.TempDir(), "main.mvm")))
+	c := start(t)
+	c.call("initialize", map[string]interface{}{}, nil)
+	c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocument{URI: uri, Text: src}})
+	if diags, ok := c.diags[uri]; !ok || len(diags) != 0 {
+		t.Errorf("diagnostics %+v, want none", diags)
+	}
+
+	h := hover{}
+	c.call("textDocument/hover", at(uri, 7, 6), &h)
+	if !strings.Contains(h.Contents.Value, "greet(:)") || h.Range == nil || *h.Range != (textRange{Start: position{7, 4}, End: position{7, 12}}) {
+		t.Errorf("hover %+v", h)
+	}
+
+	loc := location{}
+	c.call("textDocument/definition", at(uri, 7, 6), &loc)
+	if loc.URI != uri || loc.Range.Start.Line != 2 {
+		t.Errorf("definition %+v, want %s at line 2", loc, uri)
+	}
+
+	loc = location{}
+	c.call("textDocument/definition", at(uri, 3, 14), &loc)
+	dat, err := os.ReadFile(filepath.FromSlash(uriPath(loc.URI)))
+	if err != nil {
+		t.Errorf("builtin definition %+v: %s", loc, err)
+	} else if text := string(dat); !strings.Contains(text[offsetOf(text, loc.Range.Start):offsetOf(text, loc.Range.End)], "print") {
+		t.Errorf("builtin definition %+v doesn't point at 'print'", loc)
+	}
+
+	items := []completionItem{}
+	c.call("textDocument/completion", at(uri, 7, 6), &items)
+	found := false
+	for _, item := range items {
+		if item.Label == "greet(:)" {
+			found = item.TextEdit.Range == textRange{Start: position{7, 4}, End: position{7, 6}}
+		}
+	}
+	if !found {
+		t.Errorf("completion %+v doesn't replace 'gr' with 'greet(:)'", items)
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   textDocument{URI: uri},
+		"contentChanges": []map[string]string{{"text": strings.Replace(src, "greet(:)\n}\n", "nope(:)\n}\n", 1)}},
+	})
+	diags := c.diags[uri]
+	if len(diags) != 1 || diags[0].Message != "unknown fun 'nope(:)'" || diags[0].Severity != 1 ||
+		diags[0].Range != (textRange{Start: position{7, 4}, End: position{7, 11}}) {
+		t.Errorf("diagnostics %+v, want unknown fun 'nope(:)' at 7:4", diags)
+	}
+
+	c.notify("textDocument/didClose", didOpenParams{TextDocument: textDocument{URI: uri}})
+	if len(c.diags[uri]) != 0 {
+		t.Errorf("diagnostics %+v after close, want none", c.diags[uri])
+	}
+	c.stop()
+	if _, err := os.Stat(filepath.FromSlash(uriPath(loc.URI))); err == nil {
+		t.Errorf("'%s' was not removed on exit", loc.URI)
+	}
+}
@@ -2,28 +2,186 @@ package main
 
 import (
 	"bootstrap/compiler"
-	"bootstrap/lexer"
-	"bootstrap/parser"
+	"bootstrap/diag"
+	"bootstrap/isa"
+	"context"
+	"embed"
+	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-func main() {
-	dat, err := os.ReadFile(os.Args[1])
-	base := filepath.Dir(os.Args[1])
-	os.Chdir(base)
+type paths []string
+
+func (p *paths) String() string {
+	return strings.Join(*p, string(filepath.ListSeparator))
+}
+
+func (p *paths) Set(dir string) error {
+	*p = append(*p, dir)
+	return nil
+}
+
+func printDiags(diags []diag.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
+	}
+}
+
+//go:embed core
+var builtin embed.FS
+
+func searchPaths(flags paths) []string {
+	dirs := append([]string{}, flags...)
+	for _, dir := range filepath.SplitList(os.Getenv("MVM_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func loadIsa(file string) []isa.Inst {
+	if file == "" {
+		return nil
+	}
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read '%s'\n", file)
+		os.Exit(1)
+	}
+	insts, err := isa.Parse(string(dat))
 	if err != nil {
-		panic("invalid input")
+		fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+		os.Exit(1)
+	}
+	return insts
+}
+
+func compile(input string, includes paths, isaFile string, lines bool) (*compiler.Result, bool) {
+	var res *compiler.Result
+	var diags []diag.Diagnostic
+	ext := loadIsa(isaFile)
+	if strings.HasSuffix(input, ".mvasm") {
+		dat, err := os.ReadFile(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to read '%s'\n", input)
+			return nil, false
+		}
+		res, diags = compiler.Assemble(input, string(dat), ext)
+	} else {
+		res, diags = compiler.Compile(context.Background(), compiler.Options{Entry: input, SearchPaths: searchPaths(includes), Builtin: builtin, Insts: ext, BacktraceLines: lines})
+	}
+	printDiags(diags)
+	return res, !diag.HasErrors(diags)
+}
+
+func build(input string, includes paths, isaFile string, lines bool) *compiler.Result {
+	res, ok := compile(input, includes, isaFile, lines)
+	if !ok {
+		os.Exit(1)
+	}
+	return res
+}
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(args []string)
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"build", "[-I dir]... [-isa file] [-o file] [-raw] [-g] input", "compile a program into an image", buildCmd},
+		{"check", "[-I dir]... [-isa file] [-explain-stack] input...", "parse and type-check programs without generating code", checkCmd},
+		{"run", "[-I dir]... [-isa file] [-g] input [arg]...", "compile a program and run it in the embedded interpreter", runCmd},
+		{"test", "[-I dir]... [-isa file] [-g] [-update] [path]...", "run programs and compare their output with .out files", testCmd},
+		{"fmt", "[-check] [path]...", "format source files in place, or stdin to stdout", fmtCmd},
+		{"lsp", "[-I dir]... [-isa file]", "run a language server over stdio", lspCmd},
+		{"disasm", "[-I dir]... [-isa file] input", "disassemble a program or image", disasm},
+		{"symbolize", "[-I dir]... [-isa file] input [addr]...", "map code addresses back to source lines", symbolize},
+		{"help", "[command]", "show the help of a command", helpCmd},
+	}
+}
+
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n\ncommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    %-10s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(w, "\nrun '%s help <command>' for the flags of a command\n", os.Args[0])
+}
+
+func newFlagSet(name string, includes *paths, isaFile *string) *flag.FlagSet {
+	cmd := lookup(name)
+	set := flag.NewFlagSet(name, flag.ExitOnError)
+	set.Usage = func() {
+		fmt.Fprintf(set.Output(), "usage: %s %s %s\n\n%s\n", os.Args[0], cmd.name, cmd.args, cmd.short)
+		if name != "help" {
+			fmt.Fprintf(set.Output(), "\nflags:\n")
+			set.PrintDefaults()
+		}
+	}
+	if includes != nil {
+		set.Var(includes, "I", "add the directory `dir` to the import search path")
+		set.StringVar(isaFile, "isa", os.Getenv("MVM_ISA"), "load extra instructions from the isa description `file`")
+	}
+	return set
+}
+
+func usageError(set *flag.FlagSet, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	set.Usage()
+	os.Exit(2)
+}
+
+func helpCmd(args []string) {
+	set := newFlagSet("help", nil, nil)
+	set.Parse(args)
+	if set.NArg() == 0 {
+		usage(os.Stdout)
+		return
+	}
+	if set.NArg() != 1 {
+		usageError(set, "expected a single command")
+	}
+	cmd := lookup(set.Arg(0))
+	if cmd == nil {
+		usageError(set, "unknown command '%s'", set.Arg(0))
+	}
+	cmd.run([]string{"-help"})
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
 	}
-	l := lexer.New(string(dat))
-	ast, perr := parser.Parse(l)
-	if perr {
-		panic(fmt.Sprintf("error parsing file '%s'", os.Args[1]))
+	switch os.Args[1] {
+	case "-h", "-help", "--help":
+		usage(os.Stdout)
+		return
 	}
-	bytes := compiler.Compile(ast)
-	if os.WriteFile(os.Args[2], bytes, fs.FileMode(os.O_TRUNC|os.O_CREATE|os.O_RDWR)) != nil {
-		panic("invalid output")
+	cmd := lookup(os.Args[1])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
 	}
+	cmd.run(os.Args[2:])
 }
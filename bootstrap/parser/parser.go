@@ -1,130 +1,211 @@
 package parser
 
 import (
+	"bootstrap/diag"
 	"bootstrap/lexer"
-	"fmt"
+	"math/big"
 	"strings"
 )
 
-func Parse(l *lexer.Lexer) (Ast, bool) {
-	err := false
-	var lets []*Let
-	var funs []*Fun
-	var imports []*Import
-	var types []*Type
-	for token := l.Peek(); token.Typ != lexer.EOF; token = l.Peek() {
-		switch token.Typ {
-		case lexer.LET:
-			lets = append(lets, parseLet(l))
-		case lexer.FUN:
-			funs = append(funs, parseFun(l))
-		case lexer.IMPORT:
-			imports = append(imports, parseImport(l))
-		case lexer.TYPE:
-			types = append(types, parseType(l))
-		default:
-			if !err {
-				fmt.Printf("unexpected token '%s'\n", token.Typ)
+type bailout struct{}
+
+type Mode uint
+
+const (
+	Groups Mode = 1 << iota
+)
+
+type Parser struct {
+	l     *lexer.Lexer
+	mode  Mode
+	diags []diag.Diagnostic
+}
+
+func Parse(l *lexer.Lexer) (Ast, []diag.Diagnostic) {
+	return ParseMode(l, 0)
+}
+
+func ParseMode(l *lexer.Lexer, mode Mode) (Ast, []diag.Diagnostic) {
+	p := &Parser{l: l, mode: mode}
+	ast := Ast{File: l.File()}
+	for token := p.peek(); token.Typ != lexer.EOF; token = p.peek() {
+		p.parseDecl(&ast)
+	}
+	ast.Comments = l.Comments()
+	return ast, p.diags
+}
+
+func (p *Parser) parseDecl(ast *Ast) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
 			}
-			err = true
-			l.ConsumePeek()
+			p.sync()
+		}
+	}()
+	token := p.peek()
+	switch token.Typ {
+	case lexer.LET:
+		ast.Lets = append(ast.Lets, p.parseLet())
+	case lexer.FUN:
+		ast.Funs = append(ast.Funs, p.parseFun())
+	case lexer.IMPORT:
+		ast.Imports = append(ast.Imports, p.parseImport())
+	case lexer.TYPE:
+		ast.Types = append(ast.Types, p.parseType())
+	default:
+		p.errorf(token.Span, "unexpected token '%s'", token.Typ)
+		p.l.ConsumePeek()
+		p.sync()
+	}
+}
+
+func (p *Parser) sync() {
+	for token := p.peek(); token.Typ != lexer.EOF; token = p.peek() {
+		switch token.Typ {
+		case lexer.FUN, lexer.IMPORT, lexer.TYPE:
+			return
 		}
+		p.l.ConsumePeek()
 	}
-	expect(l, lexer.EOF)
-	return Ast{Funs: funs, Lets: lets, Imports: imports, Types: types}, err
 }
 
-func expect(l *lexer.Lexer, typ lexer.Typ) lexer.Token {
-	token := l.Next()
+func (p *Parser) errorf(span lexer.Span, format string, args ...interface{}) {
+	p.diags = append(p.diags, diag.Errorf(span, format, args...))
+}
+
+func (p *Parser) peek() *lexer.Token {
+	token := p.l.Peek()
+	for token.Typ == lexer.ILLEGAL {
+		p.errorf(token.Span, "%s", token.Content)
+		p.l.ConsumePeek()
+		token = p.l.Peek()
+	}
+	return token
+}
+
+func (p *Parser) next() lexer.Token {
+	token := p.l.Next()
+	for token.Typ == lexer.ILLEGAL {
+		p.errorf(token.Span, "%s", token.Content)
+		token = p.l.Next()
+	}
+	return token
+}
+
+func (p *Parser) expect(typ lexer.Typ) lexer.Token {
+	token := p.next()
 	if token.Typ != typ {
-		panic(fmt.Sprintf("unexpected token '%s' expected '%s'", token.Typ, typ))
+		p.errorf(token.Span, "unexpected token '%s' expected '%s'", token.Typ, typ)
+		panic(bailout{})
 	}
 	return token
 }
 
-func parseType(l *lexer.Lexer) *Type {
-	expect(l, lexer.TYPE)
+func (p *Parser) parseType() *Type {
+	start := p.expect(lexer.TYPE)
 	var opts []*Ident
-	if l.Peek().Typ == lexer.LBRACE {
-		opts = parseOpts(l)
+	if p.peek().Typ == lexer.LBRACE {
+		opts = p.parseOpts()
 	}
-	ident := parseIdent(l)
-	expect(l, lexer.LPAREN)
-	fields := parseTyps(l)
-	expect(l, lexer.RPAREN)
-	expect(l, lexer.SEMICOLON)
-	return &Type{Opts: opts, Ident: ident, Fields: fields}
+	ident := p.parseIdent()
+	lparen := p.expect(lexer.LPAREN)
+	fields, spans := p.parseTyps()
+	rparen := p.expect(lexer.RPAREN)
+	end := p.expect(lexer.SEMICOLON)
+	return &Type{Span: start.Span.To(end.Span), Opts: opts, Ident: ident, Fields: fields, Sig: lparen.Span.To(rparen.Span), FieldSpans: spans}
 }
 
-func parseImport(l *lexer.Lexer) *Import {
-	expect(l, lexer.IMPORT)
-	path := parseString(l)
-	expect(l, lexer.SEMICOLON)
-	return &Import{Path: path}
+func (p *Parser) parseImport() *Import {
+	start := p.expect(lexer.IMPORT)
+	path := p.parseString()
+	var alias *Ident
+	if token := p.peek(); token.Typ == lexer.IDENT && token.Content == "as" {
+		p.l.ConsumePeek()
+		alias = p.parseIdent()
+	}
+	end := p.expect(lexer.SEMICOLON)
+	return &Import{Span: start.Span.To(end.Span), Path: path, Alias: alias}
 }
 
-func parseFun(l *lexer.Lexer) *Fun {
-	expect(l, lexer.FUN)
+func (p *Parser) parseFun() *Fun {
+	start := p.expect(lexer.FUN)
 	var opts []*Ident
-	if l.Peek().Typ == lexer.LBRACE {
-		opts = parseOpts(l)
+	if p.peek().Typ == lexer.LBRACE {
+		opts = p.parseOpts()
+	}
+	ident := p.parseIdent()
+	lparen := p.expect(lexer.LPAREN)
+	inputs, inputSpans := p.parseTyps()
+	colon := p.expect(lexer.COLON)
+	outputs, outputSpans := p.parseTyps()
+	rparen := p.expect(lexer.RPAREN)
+	block := p.parseBlock()
+	return &Fun{
+		Span:        start.Span.To(block.Span),
+		Opts:        opts,
+		Ident:       ident,
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Block:       block,
+		Sig:         lparen.Span.To(rparen.Span),
+		Colon:       colon.Span,
+		InputSpans:  inputSpans,
+		OutputSpans: outputSpans,
 	}
-	ident := parseIdent(l)
-	expect(l, lexer.LPAREN)
-	inputs := parseTyps(l)
-	expect(l, lexer.COLON)
-	outputs := parseTyps(l)
-	expect(l, lexer.RPAREN)
-	block := parseBlock(l)
-	return &Fun{Opts: opts, Ident: ident, Inputs: inputs, Outputs: outputs, Block: block}
 }
 
-func parseOpts(l *lexer.Lexer) []*Ident {
-	expect(l, lexer.LBRACE)
-	idents := parseIdents(l)
-	expect(l, lexer.RBRACE)
+func (p *Parser) parseOpts() []*Ident {
+	p.expect(lexer.LBRACE)
+	idents := p.parseIdents()
+	p.expect(lexer.RBRACE)
 	return idents
 }
 
-func parseIdents(l *lexer.Lexer) []*Ident {
+func (p *Parser) parseIdents() []*Ident {
 	var idents []*Ident
 	for {
-		if l.Peek().Typ == lexer.IDENT {
-			idents = append(idents, parseIdent(l))
+		if p.peek().Typ == lexer.IDENT {
+			idents = append(idents, p.parseIdent())
 		} else {
 			return idents
 		}
-		if l.Peek().Typ == lexer.COMMA {
-			l.ConsumePeek()
+		if p.peek().Typ == lexer.COMMA {
+			p.l.ConsumePeek()
 		} else {
 			return idents
 		}
 	}
 }
 
-func parseIdent(l *lexer.Lexer) *Ident {
-	ident := expect(l, lexer.IDENT)
-	return &Ident{Content: ident.Content}
+func (p *Parser) parseIdent() *Ident {
+	token := p.expect(lexer.IDENT)
+	ident := &Ident{Content: token.Content}
+	ident.Span = token.Span
+	return ident
 }
 
-func parseTyps(l *lexer.Lexer) []Typ {
+func (p *Parser) parseTyps() ([]Typ, []lexer.Span) {
 	var typs []Typ
+	var spans []lexer.Span
 	for {
-		if l.Peek().Typ == lexer.IDENT {
-			typs = append(typs, parseTyp(l))
+		if token := p.peek(); token.Typ == lexer.IDENT {
+			spans = append(spans, token.Span)
+			typs = append(typs, p.parseTyp())
 		} else {
-			return typs
+			return typs, spans
 		}
-		if l.Peek().Typ == lexer.COMMA {
-			l.ConsumePeek()
+		if p.peek().Typ == lexer.COMMA {
+			p.l.ConsumePeek()
 		} else {
-			return typs
+			return typs, spans
 		}
 	}
 }
 
-func parseTyp(l *lexer.Lexer) Typ {
-	ident := expect(l, lexer.IDENT)
+func (p *Parser) parseTyp() Typ {
+	ident := p.expect(lexer.IDENT)
 	switch ident.Content {
 	case "u8":
 		return U8
@@ -157,206 +238,209 @@ func parseTyp(l *lexer.Lexer) Typ {
 	}
 }
 
-func parseBlock(l *lexer.Lexer) *Block {
-	expect(l, lexer.LBRACE)
-	lets := parseLets(l)
-	exprs := parseExprs(l)
-	expect(l, lexer.RBRACE)
-	return &Block{Lets: lets, Exprs: exprs}
+func (p *Parser) parseBlock() *Block {
+	start := p.expect(lexer.LBRACE)
+	lets := p.parseLets()
+	exprs := p.parseExprs()
+	end := p.expect(lexer.RBRACE)
+	return &Block{Span: start.Span.To(end.Span), Lets: lets, Exprs: exprs}
 }
 
-func parseLets(l *lexer.Lexer) []*Let {
+func (p *Parser) parseLets() []*Let {
 	var lets []*Let
 	for {
-		if l.Peek().Typ == lexer.LET {
-			lets = append(lets, parseLet(l))
+		if p.peek().Typ == lexer.LET {
+			lets = append(lets, p.parseLet())
 		} else {
 			return lets
 		}
 	}
 }
 
-func parseLet(l *lexer.Lexer) *Let {
-	expect(l, lexer.LET)
-	ident := parseIdent(l)
-	expect(l, lexer.COLON)
-	typ := parseTyp(l)
-	exprs := parseExprs(l)
-	expect(l, lexer.SEMICOLON)
-	return &Let{Ident: ident, Typ: typ, Exprs: exprs}
+func (p *Parser) parseLet() *Let {
+	start := p.expect(lexer.LET)
+	var opts []*Ident
+	if p.peek().Typ == lexer.LBRACE {
+		opts = p.parseOpts()
+	}
+	ident := p.parseIdent()
+	p.expect(lexer.COLON)
+	typ := p.parseTyp()
+	exprs := p.parseExprs()
+	end := p.expect(lexer.SEMICOLON)
+	return &Let{Span: start.Span.To(end.Span), Opts: opts, Ident: ident, Typ: typ, Exprs: exprs}
 }
 
-func parseExprs(l *lexer.Lexer) []Expr {
+func (p *Parser) parseExprs() []Expr {
 	var exprs []Expr
 	for {
-		peek := l.Peek()
+		peek := p.peek()
 		switch peek.Typ {
 		case lexer.LPAREN:
-			l.ConsumePeek()
-			exprs = append(exprs, parseExprs(l)...)
-			expect(l, lexer.RPAREN)
+			start := p.next()
+			inner := p.parseExprs()
+			end := p.expect(lexer.RPAREN)
+			if p.mode&Groups != 0 {
+				group := &Group{Exprs: inner}
+				group.Span = start.Span.To(end.Span)
+				exprs = append(exprs, group)
+			} else {
+				exprs = append(exprs, inner...)
+			}
 		case lexer.IF:
-			exprs = append(exprs, parseIf(l))
+			exprs = append(exprs, p.parseIf())
 		case lexer.IDENT:
-			exprs = append(exprs, parseIdentExpr(l))
+			exprs = append(exprs, p.parseIdentExpr())
 		case lexer.NUMBER:
-			exprs = append(exprs, parseNumber(l))
+			exprs = append(exprs, p.parseNumber())
 		case lexer.STRING:
-			exprs = append(exprs, parseString(l))
+			exprs = append(exprs, p.parseString())
 		case lexer.UNWRAP:
-			l.ConsumePeek()
-			exprs = append(exprs, &Unwrap{})
+			unwrap := &Unwrap{}
+			unwrap.Span = p.next().Span
+			exprs = append(exprs, unwrap)
 		case lexer.WRAP:
-			exprs = append(exprs, parseWrap(l))
+			exprs = append(exprs, p.parseWrap())
 		case lexer.ADDR:
-			exprs = append(exprs, parseAddr(l))
+			exprs = append(exprs, p.parseAddr())
 		case lexer.RETURN:
-			l.ConsumePeek()
-			exprs = append(exprs, &Return{})
+			ret := &Return{}
+			ret.Span = p.next().Span
+			exprs = append(exprs, ret)
 		case lexer.WHILE:
-			exprs = append(exprs, parseWhile(l))
+			exprs = append(exprs, p.parseWhile())
+		case lexer.HOLE:
+			hole := &Hole{}
+			hole.Span = p.next().Span
+			exprs = append(exprs, hole)
 		default:
 			return exprs
 		}
 	}
 }
 
-func parseAddr(l *lexer.Lexer) *Addr {
+func (p *Parser) parseAddr() *Addr {
 	var ident *Ident
 	var call *Call
-	expect(l, lexer.ADDR)
-	expect(l, lexer.LPAREN)
-	_ident := parseIdent(l)
-	if l.RawPeek().Typ == lexer.LPAREN {
-		l.ConsumePeek()
-		inputs := parseTyps(l)
-		expect(l, lexer.COLON)
-		outputs := parseTyps(l)
-		expect(l, lexer.RPAREN)
+	start := p.expect(lexer.ADDR)
+	p.expect(lexer.LPAREN)
+	_ident := p.parseIdent()
+	if p.l.RawPeek().Typ == lexer.LPAREN {
+		p.l.ConsumePeek()
+		inputs, _ := p.parseTyps()
+		p.expect(lexer.COLON)
+		outputs, _ := p.parseTyps()
+		rparen := p.expect(lexer.RPAREN)
 		call = &Call{Ident: _ident, Inputs: inputs, Outputs: outputs}
+		call.Span = _ident.Span.To(rparen.Span)
 	} else {
 		ident = _ident
 	}
-	expect(l, lexer.RPAREN)
-	return &Addr{Ident: ident, Call: call}
+	end := p.expect(lexer.RPAREN)
+	addr := &Addr{Ident: ident, Call: call}
+	addr.Span = start.Span.To(end.Span)
+	return addr
 }
 
-func parseWrap(l *lexer.Lexer) *Wrap {
-	expect(l, lexer.WRAP)
-	expect(l, lexer.LPAREN)
-	typ := parseTyp(l)
-	expect(l, lexer.RPAREN)
-	return &Wrap{Typ: typ}
+func (p *Parser) parseWrap() *Wrap {
+	start := p.expect(lexer.WRAP)
+	p.expect(lexer.LPAREN)
+	typ := p.parseTyp()
+	end := p.expect(lexer.RPAREN)
+	wrap := &Wrap{Typ: typ}
+	wrap.Span = start.Span.To(end.Span)
+	return wrap
 }
 
-func parseIf(l *lexer.Lexer) *If {
-	expect(l, lexer.IF)
-	expect(l, lexer.LPAREN)
-	con := parseExprs(l)
-	expect(l, lexer.RPAREN)
-	expect(l, lexer.LBRACE)
-	exprs := parseExprs(l)
-	expect(l, lexer.RBRACE)
-	els := []Expr{}
-	if l.Peek().Typ == lexer.ELSE {
-		l.ConsumePeek()
-		expect(l, lexer.LBRACE)
-		els = parseExprs(l)
-		expect(l, lexer.RBRACE)
+func (p *Parser) parseIf() *If {
+	start := p.expect(lexer.IF)
+	lparen := p.expect(lexer.LPAREN)
+	con := p.parseExprs()
+	rparen := p.expect(lexer.RPAREN)
+	lbrace := p.expect(lexer.LBRACE)
+	exprs := p.parseExprs()
+	end := p.expect(lexer.RBRACE)
+	ifel := &If{Con: con, Exprs: exprs, Else: []Expr{}, ConSpan: lparen.Span.To(rparen.Span), ExprsSpan: lbrace.Span.To(end.Span)}
+	if p.peek().Typ == lexer.ELSE {
+		p.l.ConsumePeek()
+		lbrace = p.expect(lexer.LBRACE)
+		ifel.Else = p.parseExprs()
+		end = p.expect(lexer.RBRACE)
+		ifel.ElseSpan = lbrace.Span.To(end.Span)
 	}
-	return &If{Con: con, Exprs: exprs, Else: els}
+	ifel.Span = start.Span.To(end.Span)
+	return ifel
 }
 
-func parseWhile(l *lexer.Lexer) *While {
-	expect(l, lexer.WHILE)
-	expect(l, lexer.LPAREN)
-	con := parseExprs(l)
-	expect(l, lexer.RPAREN)
-	expect(l, lexer.LBRACE)
-	exprs := parseExprs(l)
-	expect(l, lexer.RBRACE)
-	return &While{Con: con, Exprs: exprs}
+func (p *Parser) parseWhile() *While {
+	start := p.expect(lexer.WHILE)
+	lparen := p.expect(lexer.LPAREN)
+	con := p.parseExprs()
+	rparen := p.expect(lexer.RPAREN)
+	lbrace := p.expect(lexer.LBRACE)
+	exprs := p.parseExprs()
+	end := p.expect(lexer.RBRACE)
+	while := &While{Con: con, Exprs: exprs, ConSpan: lparen.Span.To(rparen.Span), ExprsSpan: lbrace.Span.To(end.Span)}
+	while.Span = start.Span.To(end.Span)
+	return while
 }
 
-func parseIdentExpr(l *lexer.Lexer) Expr {
-	ident := parseIdent(l)
-	if l.RawPeek().Typ == lexer.LPAREN {
-		l.ConsumePeek()
-		inputs := parseTyps(l)
-		expect(l, lexer.COLON)
-		outputs := parseTyps(l)
-		expect(l, lexer.RPAREN)
-		return &Call{Ident: ident, Inputs: inputs, Outputs: outputs}
+func (p *Parser) parseIdentExpr() Expr {
+	ident := p.parseIdent()
+	if p.l.RawPeek().Typ == lexer.LPAREN {
+		p.l.ConsumePeek()
+		inputs, _ := p.parseTyps()
+		p.expect(lexer.COLON)
+		outputs, _ := p.parseTyps()
+		end := p.expect(lexer.RPAREN)
+		call := &Call{Ident: ident, Inputs: inputs, Outputs: outputs}
+		call.Span = ident.Span.To(end.Span)
+		return call
 	} else {
 		return ident
 	}
 }
 
-func parseNumber(l *lexer.Lexer) *Number {
-	number := expect(l, lexer.NUMBER)
+func (p *Parser) parseNumber() *Number {
+	number := p.expect(lexer.NUMBER)
 	var end int
-	var typ Typ
-	var size int
 
 	base := 10
 	start := 0
+	sign := ""
 
-	if strings.HasPrefix(number.Content, "0x") {
+	if strings.HasPrefix(number.Content, "-") {
+		sign = "-"
+		start = 1
+	}
+	if strings.HasPrefix(number.Content[start:], "0x") {
 		base = 16
-		start = 2
-	} else if strings.HasPrefix(number.Content, "0b") {
+		start += 2
+	} else if strings.HasPrefix(number.Content[start:], "0b") {
 		base = 2
-		start = 2
+		start += 2
 	}
 
-	if strings.HasSuffix(number.Content, "u8") {
-		end = 2
-		typ = U8
-		size = 1
-	} else if strings.HasSuffix(number.Content, "u16") {
-		end = 3
-		typ = U16
-		size = 2
-	} else if strings.HasSuffix(number.Content, "u32") {
-		end = 3
-		typ = U32
-		size = 4
-	} else if strings.HasSuffix(number.Content, "u64") {
-		end = 3
-		typ = U64
-		size = 8
-	} else if strings.HasSuffix(number.Content, "u128") {
-		end = 4
-		typ = U128
-		size = 16
-	} else if strings.HasSuffix(number.Content, "i8") {
-		end = 2
-		typ = I8
-		size = 1
-	} else if strings.HasSuffix(number.Content, "i16") {
-		end = 3
-		typ = I16
-		size = 2
-	} else if strings.HasSuffix(number.Content, "i32") {
-		end = 3
-		typ = I32
-		size = 4
-	} else if strings.HasSuffix(number.Content, "i64") {
-		end = 3
-		typ = I64
-		size = 8
-	} else if strings.HasSuffix(number.Content, "i128") {
-		end = 4
-		typ = I128
-		size = 16
-	} else {
-		panic(fmt.Sprintf("number '%s' is missing a type", number.Content))
+	n := &Number{Base: base}
+	for _, typ := range numTyps {
+		suffix := strings.ToLower(string(typ))
+		if strings.HasSuffix(number.Content, suffix) {
+			end = len(suffix)
+			n.SetTyp(typ)
+			break
+		}
+	}
+	n.Content = sign + number.Content[start:len(number.Content)-end]
+	n.Span = number.Span
+	if _, ok := new(big.Int).SetString(n.Content, base); !ok {
+		p.errorf(number.Span, "invalid number literal '%s'", number.Content)
 	}
-	content := number.Content[start : len(number.Content)-end]
-	return &Number{Content: content, Base: base, Size: size, Typ: typ}
+	return n
 }
 
-func parseString(l *lexer.Lexer) *String {
-	str := expect(l, lexer.STRING)
-	return &String{Content: str.Content}
+func (p *Parser) parseString() *String {
+	token := p.expect(lexer.STRING)
+	str := &String{Content: token.Content}
+	str.Span = token.Span
+	return str
 }
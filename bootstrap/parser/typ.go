@@ -1,24 +1,36 @@
 package parser
 
-import "fmt"
+import (
+	"bootstrap/lexer"
+	"fmt"
+)
 
 type Ast struct {
-	Imports []*Import
-	Lets    []*Let
-	Funs    []*Fun
-	Types   []*Type
+	File     string
+	Imports  []*Import
+	Lets     []*Let
+	Funs     []*Fun
+	Types    []*Type
+	Comments []lexer.Token
 }
 
 type Import struct {
-	Path *String
+	Span  lexer.Span
+	Path  *String
+	Alias *Ident
 }
 
 type Fun struct {
-	Opts    []*Ident
-	Ident   *Ident
-	Inputs  []Typ
-	Outputs []Typ
-	Block   *Block
+	Span        lexer.Span
+	Opts        []*Ident
+	Ident       *Ident
+	Inputs      []Typ
+	Outputs     []Typ
+	Block       *Block
+	Sig         lexer.Span
+	Colon       lexer.Span
+	InputSpans  []lexer.Span
+	OutputSpans []lexer.Span
 }
 
 type Ident struct {
@@ -47,6 +59,10 @@ func (ts *Types) Set(ident string, typ *Type) bool {
 	return false
 }
 
+func (ts *Types) Has(ident string) bool {
+	return ts.ts[ident] != nil
+}
+
 func (ts *Types) Get(ident string) *Type {
 	typ := ts.ts[ident]
 	if typ == nil {
@@ -129,6 +145,8 @@ func (b Builtin) Sub(*Types) []Typ {
 		return []Typ{U32}
 	case U64:
 		return []Typ{I64}
+	case I64:
+		return []Typ{U64}
 	case U128:
 		return []Typ{I128}
 	case I128:
@@ -141,9 +159,12 @@ func (b Builtin) Sub(*Types) []Typ {
 }
 
 type Type struct {
-	Opts   []*Ident
-	Ident  *Ident
-	Fields []Typ
+	Span       lexer.Span
+	Opts       []*Ident
+	Ident      *Ident
+	Fields     []Typ
+	Sig        lexer.Span
+	FieldSpans []lexer.Span
 }
 
 func (c *Custom) IsNever() bool {
@@ -192,17 +213,21 @@ const (
 )
 
 type Block struct {
+	Span  lexer.Span
 	Lets  []*Let
 	Exprs []Expr
 }
 
 type Let struct {
+	Span  lexer.Span
+	Opts  []*Ident
 	Ident *Ident
 	Typ   Typ
 	Exprs []Expr
 }
 
 type Expr interface {
+	GetSpan() lexer.Span
 	AsIdent() *Ident
 	AsCall() *Call
 	AsNumber() *Number
@@ -213,9 +238,17 @@ type Expr interface {
 	AsAddr() *Addr
 	AsReturn() *Return
 	AsWhile() *While
+	AsGroup() *Group
+	AsHole() *Hole
+}
+
+type DefaultExpr struct {
+	Span lexer.Span
 }
 
-type DefaultExpr struct{}
+func (e *DefaultExpr) GetSpan() lexer.Span {
+	return e.Span
+}
 
 func (e DefaultExpr) AsWrap() *Wrap {
 	return nil
@@ -257,6 +290,14 @@ func (e *DefaultExpr) AsWhile() *While {
 	return nil
 }
 
+func (e *DefaultExpr) AsGroup() *Group {
+	return nil
+}
+
+func (e *DefaultExpr) AsHole() *Hole {
+	return nil
+}
+
 type Call struct {
 	DefaultExpr
 	Ident   *Ident
@@ -274,12 +315,27 @@ type Number struct {
 	Typ     Typ
 	Size    int
 	Base    int
+	Signed  bool
 }
 
 func (e *Number) AsNumber() *Number {
 	return e
 }
 
+var numTyps = [...]Builtin{U8, U16, U32, U64, U128, I8, I16, I32, I64, I128}
+
+func (e *Number) SetTyp(typ Typ) bool {
+	for _, num := range numTyps {
+		if typ == num {
+			e.Typ = num
+			e.Size = num.Size(nil)
+			e.Signed = num == I8 || num == I16 || num == I32 || num == I64 || num == I128
+			return true
+		}
+	}
+	return false
+}
+
 type String struct {
 	DefaultExpr
 	Content string
@@ -291,9 +347,12 @@ func (e *String) AsString() *String {
 
 type If struct {
 	DefaultExpr
-	Con   []Expr
-	Exprs []Expr
-	Else  []Expr
+	Con       []Expr
+	Exprs     []Expr
+	Else      []Expr
+	ConSpan   lexer.Span
+	ExprsSpan lexer.Span
+	ElseSpan  lexer.Span
 }
 
 func (e *If) AsIf() *If {
@@ -302,8 +361,10 @@ func (e *If) AsIf() *If {
 
 type While struct {
 	DefaultExpr
-	Con   []Expr
-	Exprs []Expr
+	Con       []Expr
+	Exprs     []Expr
+	ConSpan   lexer.Span
+	ExprsSpan lexer.Span
 }
 
 func (e *While) AsWhile() *While {
@@ -344,3 +405,20 @@ type Return struct {
 func (e *Return) AsReturn() *Return {
 	return e
 }
+
+type Group struct {
+	DefaultExpr
+	Exprs []Expr
+}
+
+func (e *Group) AsGroup() *Group {
+	return e
+}
+
+type Hole struct {
+	DefaultExpr
+}
+
+func (e *Hole) AsHole() *Hole {
+	return e
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bootstrap/vm"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func load(input string, includes paths, isaFile string, lines bool, args *string) *vm.VM {
+	if strings.HasSuffix(input, ".mvm") || strings.HasSuffix(input, ".mvasm") {
+		return vm.New(build(input, includes, isaFile, lines).Bytes, args)
+	}
+	dat, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read '%s'\n", input)
+		os.Exit(1)
+	}
+	m, err := vm.Load(dat, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid image '%s': %s\n", input, err)
+		os.Exit(1)
+	}
+	return m
+}
+
+func runCmd(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("run", &includes, &isaFile)
+	lines := set.Bool("g", false, "include call site source lines in backtraces")
+	set.Parse(args)
+	if set.NArg() == 0 {
+		usageError(set, "expected an input")
+	}
+	var progArgs *string
+	if set.NArg() > 1 {
+		joined := strings.Join(set.Args()[1:], " ")
+		progArgs = &joined
+	}
+	m := load(set.Arg(0), includes, isaFile, *lines, progArgs)
+	if err := m.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	if m.Status() != 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bootstrap/compiler"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func printLine(line compiler.Line) {
+	fmt.Printf("0x%04x-0x%04x %s:%d:%d %s\n", line.Addr, line.Addr+line.Size, line.Span.File, line.Span.Start.Line, line.Span.Start.Col, line.Fun)
+}
+
+func symbolize(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("symbolize", &includes, &isaFile)
+	set.Parse(args)
+	if set.NArg() < 1 {
+		usageError(set, "expected an input")
+	}
+	res := loadResult(set.Arg(0), includes, isaFile)
+	if len(res.Lines) == 0 {
+		fmt.Fprintf(os.Stderr, "'%s' has no debug info\n", set.Arg(0))
+		os.Exit(1)
+	}
+	if set.NArg() == 1 {
+		for _, line := range res.Lines {
+			printLine(line)
+		}
+		return
+	}
+	for _, arg := range set.Args()[1:] {
+		addr, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid address '%s'\n", arg)
+			os.Exit(1)
+		}
+		fmt.Printf("0x%04x:\n", addr)
+		for _, line := range res.LinesAt(addr) {
+			fmt.Print("    ")
+			printLine(line)
+		}
+	}
+}
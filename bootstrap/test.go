@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bootstrap/vm"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func testFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s'", path)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(file, ".mvm") {
+				return err
+			}
+			if _, err := os.Stat(outFile(file)); err == nil {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s'", path)
+		}
+	}
+	return files, nil
+}
+
+func outFile(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".out"
+}
+
+func runTest(file string, includes paths, isaFile string, lines bool, update bool) bool {
+	res, ok := compile(file, includes, isaFile, lines)
+	if !ok {
+		return false
+	}
+	out := &bytes.Buffer{}
+	m := vm.New(res.Bytes, nil)
+	m.Stdin = &bytes.Buffer{}
+	m.Stdout = out
+	if err := m.Run(); err != nil {
+		fmt.Fprintf(out, "error: %s\n", err)
+	}
+	if update {
+		if os.WriteFile(outFile(file), out.Bytes(), 0644) != nil {
+			fmt.Fprintf(os.Stderr, "invalid output '%s'\n", outFile(file))
+			return false
+		}
+		return true
+	}
+	expected, err := os.ReadFile(outFile(file))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read '%s'\n", outFile(file))
+		return false
+	}
+	if bytes.Equal(expected, out.Bytes()) {
+		return true
+	}
+	fmt.Printf("--- expected\n%s\n--- actual\n%s\n", expected, out.Bytes())
+	return false
+}
+
+func testCmd(args []string) {
+	var includes paths
+	var isaFile string
+	set := newFlagSet("test", &includes, &isaFile)
+	lines := set.Bool("g", false, "include call site source lines in backtraces")
+	update := set.Bool("update", false, "write the actual output to the .out files")
+	set.Parse(args)
+	dirs := set.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	files, err := testFiles(dirs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("no tests found")
+		return
+	}
+	failed := 0
+	for _, file := range files {
+		if runTest(file, includes, isaFile, *lines, *update) {
+			fmt.Printf("ok   %s\n", file)
+		} else {
+			fmt.Printf("FAIL %s\n", file)
+			failed++
+		}
+	}
+	if failed != 0 {
+		fmt.Printf("%d of %d tests failed\n", failed, len(files))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,752 @@
+package vm
+
+import (
+	"bootstrap/image"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+)
+
+type Inter int8
+
+const (
+	OUT_OF_MEM   Inter = -1
+	REG_OVERFLOW Inter = -2
+	INVALID_INST Inter = -3
+	IO_ERROR     Inter = -4
+)
+
+func (i Inter) Error() string {
+	switch i {
+	case OUT_OF_MEM:
+		return "out of memory"
+	case REG_OVERFLOW:
+		return "register overflow"
+	case INVALID_INST:
+		return "invalid instruction"
+	case IO_ERROR:
+		return "io error"
+	default:
+		return fmt.Sprintf("interrupt '%d'", int8(i))
+	}
+}
+
+var ErrDivByZero = errors.New("division by zero")
+
+var widths = [...]int{1, 2, 4, 8, 16}
+
+var convs = [...][2]int{
+	{1, 2}, {1, 4}, {1, 8}, {1, 16},
+	{2, 1}, {2, 4}, {2, 8}, {2, 16},
+	{4, 1}, {4, 2}, {4, 8}, {4, 16},
+	{8, 1}, {8, 2}, {8, 4}, {8, 16},
+	{16, 1}, {16, 2}, {16, 4}, {16, 8},
+}
+
+type VM struct {
+	pc     uint64
+	sp     uint64
+	base   uint64
+	cs     uint64
+	ih     uint64
+	ir     int8
+	mem    []uint8
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+func New(code []uint8, args *string) *VM {
+	mem := append([]uint8{}, code...)
+	ptr := uint64(len(mem))
+	buf := make([]uint8, 8)
+	if args != nil {
+		mem = append(mem, *args...)
+		binary.LittleEndian.PutUint64(buf, uint64(len(*args)))
+	}
+	mem = append(mem, buf...)
+	binary.LittleEndian.PutUint64(buf, ptr)
+	mem = append(mem, buf...)
+	sp := uint64(len(mem)) - 16
+	return &VM{sp: sp, base: sp, mem: mem, Stdin: os.Stdin, Stdout: os.Stdout}
+}
+
+func Load(bytes []uint8, args *string) (*VM, error) {
+	if !image.IsImage(bytes) {
+		return New(bytes, args), nil
+	}
+	img, err := image.Decode(bytes)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := img.Memory()
+	if err != nil {
+		return nil, err
+	}
+	vm := New(mem, args)
+	vm.pc = img.Entry
+	return vm, nil
+}
+
+func (vm *VM) Run() error {
+	for {
+		err := vm.run()
+		inter, ok := err.(Inter)
+		if !ok {
+			return err
+		}
+		vm.ir = int8(inter)
+		vm.pc = vm.ih
+	}
+}
+
+func (vm *VM) Status() int8 {
+	return vm.ir
+}
+
+func add(reg uint64, val uint64) (uint64, error) {
+	res := reg + val
+	if res < reg {
+		return 0, REG_OVERFLOW
+	}
+	return res, nil
+}
+
+func sub(reg uint64, val uint64) (uint64, error) {
+	if val > reg {
+		return 0, REG_OVERFLOW
+	}
+	return reg - val, nil
+}
+
+func (vm *VM) read(addr uint64, n int) ([]uint8, error) {
+	if addr+uint64(n) < addr || addr+uint64(n) > uint64(len(vm.mem)) {
+		return nil, OUT_OF_MEM
+	}
+	return vm.mem[addr : addr+uint64(n)], nil
+}
+
+func (vm *VM) write(addr uint64, val []uint8) error {
+	dst, err := vm.read(addr, len(val))
+	if err != nil {
+		return err
+	}
+	copy(dst, val)
+	return nil
+}
+
+func (vm *VM) push(val []uint8) error {
+	sp, err := sub(vm.sp, uint64(len(val)))
+	if err != nil {
+		return err
+	}
+	vm.sp = sp
+	return vm.write(vm.sp, val)
+}
+
+func (vm *VM) pop(n int) ([]uint8, error) {
+	val, err := vm.read(vm.sp, n)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := add(vm.sp, uint64(n))
+	if err != nil {
+		return nil, err
+	}
+	vm.sp = sp
+	return append([]uint8{}, val...), nil
+}
+
+func (vm *VM) popU64() (uint64, error) {
+	val, err := vm.pop(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(val), nil
+}
+
+func (vm *VM) pushU64(val uint64) error {
+	buf := make([]uint8, 8)
+	binary.LittleEndian.PutUint64(buf, val)
+	return vm.push(buf)
+}
+
+func (vm *VM) imm() (uint64, error) {
+	pc, err := add(vm.pc, 1)
+	if err != nil {
+		return 0, err
+	}
+	val, err := vm.read(pc, 8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(val), nil
+}
+
+func unsigned(val []uint8) *big.Int {
+	buf := make([]uint8, len(val))
+	for i := range val {
+		buf[len(val)-1-i] = val[i]
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+func signed(val []uint8) *big.Int {
+	x := unsigned(val)
+	if val[len(val)-1]&0x80 != 0 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(8*len(val))))
+	}
+	return x
+}
+
+func bytesOf(x *big.Int, n int) []uint8 {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	x = new(big.Int).Mod(x, mod)
+	buf := x.FillBytes(make([]uint8, n))
+	for i := 0; i < n/2; i++ {
+		buf[i], buf[n-1-i] = buf[n-1-i], buf[i]
+	}
+	return buf
+}
+
+func boolOf(b bool) []uint8 {
+	if b {
+		return []uint8{1}
+	}
+	return []uint8{0}
+}
+
+func (vm *VM) binary(n int, m int, op func(a []uint8, b []uint8) ([]uint8, error)) error {
+	b, err := vm.pop(m)
+	if err != nil {
+		return err
+	}
+	a, err := vm.pop(n)
+	if err != nil {
+		return err
+	}
+	res, err := op(a, b)
+	if err != nil {
+		return err
+	}
+	return vm.push(res)
+}
+
+func (vm *VM) arith(inst uint8, n int, sig bool) error {
+	num := unsigned
+	if sig {
+		num = signed
+	}
+	return vm.binary(n, n, func(a []uint8, b []uint8) ([]uint8, error) {
+		x, y := num(a), num(b)
+		switch inst / 10 {
+		case 10:
+			return bytesOf(x.Add(x, y), n), nil
+		case 11:
+			return bytesOf(x.Sub(x, y), n), nil
+		case 12:
+			return bytesOf(x.Mul(x, y), n), nil
+		case 13:
+			if y.Sign() == 0 {
+				return nil, ErrDivByZero
+			}
+			return bytesOf(x.Quo(x, y), n), nil
+		case 14:
+			if y.Sign() == 0 {
+				return nil, ErrDivByZero
+			}
+			return bytesOf(x.Rem(x, y), n), nil
+		case 15:
+			return boolOf(x.Cmp(y) < 0), nil
+		case 16:
+			return boolOf(x.Cmp(y) <= 0), nil
+		case 17:
+			return boolOf(x.Cmp(y) > 0), nil
+		default:
+			return boolOf(x.Cmp(y) >= 0), nil
+		}
+	})
+}
+
+func (vm *VM) bits(inst uint8, n int) error {
+	m := n
+	if inst >= 60 && inst < 80 {
+		m = 1
+	}
+	return vm.binary(n, m, func(a []uint8, b []uint8) ([]uint8, error) {
+		x, y := unsigned(a), unsigned(b)
+		size := uint(8 * n)
+		shift := uint(y.Uint64()) % size
+		switch {
+		case inst >= 50 && inst < 55:
+			return bytesOf(x.And(x, y), n), nil
+		case inst >= 55 && inst < 60:
+			return bytesOf(x.Or(x, y), n), nil
+		case inst >= 60 && inst < 65:
+			return bytesOf(x.Lsh(x, shift), n), nil
+		case inst >= 65 && inst < 70:
+			return bytesOf(x.Rsh(x, shift), n), nil
+		case inst >= 70 && inst < 75:
+			l := new(big.Int).Lsh(x, shift)
+			return bytesOf(l.Or(l, x.Rsh(x, size-shift)), n), nil
+		case inst >= 75 && inst < 80:
+			r := new(big.Int).Rsh(x, shift)
+			return bytesOf(r.Or(r, x.Lsh(x, size-shift)), n), nil
+		case inst >= 80 && inst < 85:
+			return boolOf(x.Cmp(y) == 0), nil
+		case inst >= 85 && inst < 90:
+			return boolOf(x.Cmp(y) != 0), nil
+		default:
+			return bytesOf(x.Xor(x, y), n), nil
+		}
+	})
+}
+
+func (vm *VM) str() (string, error) {
+	len, err := vm.popU64()
+	if err != nil {
+		return "", err
+	}
+	ptr, err := vm.popU64()
+	if err != nil {
+		return "", err
+	}
+	buf, err := vm.read(ptr, int(len))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (vm *VM) slice() ([]uint8, error) {
+	len, err := vm.popU64()
+	if err != nil {
+		return nil, err
+	}
+	ptr, err := vm.popU64()
+	if err != nil {
+		return nil, err
+	}
+	return vm.read(ptr, int(len))
+}
+
+func (vm *VM) Stack() []uint8 {
+	if vm.sp > vm.base || vm.base > uint64(len(vm.mem)) {
+		return nil
+	}
+	return append([]uint8{}, vm.mem[vm.sp:vm.base]...)
+}
+
+func (vm *VM) String() string {
+	return fmt.Sprintf("VM { pc: 0x%x, sp: 0x%x, cs: 0x%x, ih: 0x%x, ir: %d }", vm.pc, vm.sp, vm.cs, vm.ih, vm.ir)
+}
+
+func (vm *VM) run() error {
+	for {
+		code, err := vm.read(vm.pc, 1)
+		if err != nil {
+			return err
+		}
+		inst := code[0]
+		next := uint64(1)
+		switch {
+		case inst == 0:
+		case inst == 1:
+			return nil
+		case inst == 2:
+			addr, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			if err := vm.call(addr, 1); err != nil {
+				return err
+			}
+			continue
+		case inst == 3:
+			ret, err := vm.read(vm.cs, 8)
+			if err != nil {
+				return err
+			}
+			cs, err := add(vm.cs, 8)
+			if err != nil {
+				return err
+			}
+			vm.cs = cs
+			vm.pc = binary.LittleEndian.Uint64(ret)
+			continue
+		case inst == 4:
+			vm.pc = vm.ih
+			continue
+		case inst == 5:
+			cap, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			ptr := uint64(len(vm.mem))
+			vm.mem = append(vm.mem, make([]uint8, cap)...)
+			if err := vm.pushU64(ptr); err != nil {
+				return err
+			}
+		case inst == 6:
+			buf, err := vm.slice()
+			if err != nil {
+				return err
+			}
+			n, err := vm.Stdin.Read(buf)
+			if err != nil && err != io.EOF {
+				return IO_ERROR
+			}
+			if err := vm.pushU64(uint64(n)); err != nil {
+				return err
+			}
+		case inst == 7:
+			buf, err := vm.slice()
+			if err != nil {
+				return err
+			}
+			n, err := vm.Stdout.Write(buf)
+			if err != nil {
+				return IO_ERROR
+			}
+			if err := vm.pushU64(uint64(n)); err != nil {
+				return err
+			}
+		case inst == 8:
+			dst, err := vm.slice()
+			if err != nil {
+				return err
+			}
+			path, err := vm.str()
+			if err != nil {
+				return err
+			}
+			dat, _ := os.ReadFile(path)
+			n := copy(dst, dat)
+			if err := vm.pushU64(uint64(n)); err != nil {
+				return err
+			}
+		case inst == 9:
+			src, err := vm.slice()
+			if err != nil {
+				return err
+			}
+			path, err := vm.str()
+			if err != nil {
+				return err
+			}
+			n := len(src)
+			if os.WriteFile(path, src, 0644) != nil {
+				n = 0
+			}
+			if err := vm.pushU64(uint64(n)); err != nil {
+				return err
+			}
+		case inst >= 10 && inst < 15:
+			n := widths[inst-10]
+			pc, err := add(vm.pc, 1)
+			if err != nil {
+				return err
+			}
+			val, err := vm.read(pc, n)
+			if err != nil {
+				return err
+			}
+			if err := vm.push(append([]uint8{}, val...)); err != nil {
+				return err
+			}
+			next += uint64(n)
+		case inst >= 15 && inst < 18:
+			val, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			switch inst {
+			case 15:
+				vm.sp = val
+				vm.base = val
+			case 16:
+				vm.cs = val
+			default:
+				vm.ih = val
+			}
+		case inst == 18:
+			val, err := vm.pop(1)
+			if err != nil {
+				return err
+			}
+			vm.ir = int8(val[0])
+		case inst == 19:
+			if err := vm.push([]uint8{uint8(vm.ir)}); err != nil {
+				return err
+			}
+		case inst >= 20 && inst < 25:
+			if _, err := vm.pop(widths[inst-20]); err != nil {
+				return err
+			}
+		case inst >= 25 && inst < 30:
+			n := widths[inst-25]
+			val, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			x := signed(val)
+			max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(8*n-1)), big.NewInt(1))
+			x.Neg(x)
+			if x.Cmp(max) > 0 {
+				x = max
+			}
+			if err := vm.push(bytesOf(x, n)); err != nil {
+				return err
+			}
+		case inst >= 30 && inst < 35:
+			n := widths[inst-30]
+			v1, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			v2, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			if err := vm.push(v1); err != nil {
+				return err
+			}
+			if err := vm.push(v2); err != nil {
+				return err
+			}
+		case inst >= 35 && inst < 40:
+			n := widths[inst-35]
+			v1, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			v2, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			v3, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			for _, v := range [][]uint8{v2, v1, v3} {
+				if err := vm.push(v); err != nil {
+					return err
+				}
+			}
+		case inst >= 40 && inst < 50:
+			n := widths[(inst-40)%5]
+			addr := vm.sp
+			if inst >= 45 {
+				if addr, err = add(vm.sp, uint64(n)); err != nil {
+					return err
+				}
+			}
+			val, err := vm.read(addr, n)
+			if err != nil {
+				return err
+			}
+			if err := vm.push(append([]uint8{}, val...)); err != nil {
+				return err
+			}
+		case inst >= 50 && inst < 90:
+			if err := vm.bits(inst, widths[inst%5]); err != nil {
+				return err
+			}
+		case inst == 90 || inst == 91 || inst == 92:
+			val, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			if err := vm.jump(inst-90, val); err != nil {
+				return err
+			}
+			continue
+		case inst == 94:
+			val, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			time.Sleep(time.Duration(val) * time.Millisecond)
+		case inst == 95 || inst == 96 || inst == 97:
+			con, err := vm.pop(1)
+			if err != nil {
+				return err
+			}
+			val, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			if con[0] != 0 {
+				if err := vm.jump(inst-95, val); err != nil {
+					return err
+				}
+				continue
+			}
+		case inst == 98:
+			if err := vm.pushU64(vm.cs); err != nil {
+				return err
+			}
+		case inst >= 100 && inst < 190:
+			if err := vm.arith(inst, widths[inst%5], inst%10 >= 5); err != nil {
+				return err
+			}
+		case inst >= 190 && inst < 210:
+			conv := convs[inst-190]
+			val, err := vm.pop(conv[0])
+			if err != nil {
+				return err
+			}
+			if err := vm.push(bytesOf(unsigned(val), conv[1])); err != nil {
+				return err
+			}
+		case inst >= 210 && inst < 215:
+			addr, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			val, err := vm.read(addr, widths[inst-210])
+			if err != nil {
+				return err
+			}
+			if err := vm.push(append([]uint8{}, val...)); err != nil {
+				return err
+			}
+		case inst >= 215 && inst < 220:
+			val, err := vm.pop(widths[inst-215])
+			if err != nil {
+				return err
+			}
+			addr, err := vm.popU64()
+			if err != nil {
+				return err
+			}
+			if err := vm.write(addr, val); err != nil {
+				return err
+			}
+		case inst == 220 || inst == 221 || inst == 222:
+			val, err := vm.imm()
+			if err != nil {
+				return err
+			}
+			if err := vm.jump(inst-220, val); err != nil {
+				return err
+			}
+			continue
+		case inst == 224:
+			val, err := vm.imm()
+			if err != nil {
+				return err
+			}
+			time.Sleep(time.Duration(val) * time.Millisecond)
+			next = 9
+		case inst == 225 || inst == 226 || inst == 227:
+			con, err := vm.pop(1)
+			if err != nil {
+				return err
+			}
+			if con[0] != 0 {
+				val, err := vm.imm()
+				if err != nil {
+					return err
+				}
+				if err := vm.jump(inst-225, val); err != nil {
+					return err
+				}
+				continue
+			}
+			next = 9
+		case inst == 229:
+			val, err := vm.imm()
+			if err != nil {
+				return err
+			}
+			if err := vm.call(val, 9); err != nil {
+				return err
+			}
+			continue
+		case inst >= 230 && inst < 235:
+			addr, err := vm.imm()
+			if err != nil {
+				return err
+			}
+			val, err := vm.read(addr, widths[inst-230])
+			if err != nil {
+				return err
+			}
+			if err := vm.push(append([]uint8{}, val...)); err != nil {
+				return err
+			}
+			next = 9
+		case inst >= 235 && inst < 240:
+			val, err := vm.pop(widths[inst-235])
+			if err != nil {
+				return err
+			}
+			addr, err := vm.imm()
+			if err != nil {
+				return err
+			}
+			if err := vm.write(addr, val); err != nil {
+				return err
+			}
+			next = 9
+		case inst >= 240 && inst < 245:
+			if err := vm.bits(inst, widths[inst-240]); err != nil {
+				return err
+			}
+		case inst == 250:
+			fmt.Fprintln(vm.Stdout, vm)
+			fmt.Fprintln(vm.Stdout, hex.Dump(vm.mem))
+		case inst >= 251:
+			n := widths[inst-251]
+			val, err := vm.pop(n)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(vm.Stdout, "Debug%d: 0x%x\n", 8*n, unsigned(val))
+		default:
+			return INVALID_INST
+		}
+		pc, err := add(vm.pc, next)
+		if err != nil {
+			return err
+		}
+		vm.pc = pc
+	}
+}
+
+func (vm *VM) jump(mode uint8, val uint64) error {
+	var err error
+	switch mode {
+	case 0:
+		vm.pc = val
+	case 1:
+		vm.pc, err = add(vm.pc, val)
+	default:
+		vm.pc, err = sub(vm.pc, val)
+	}
+	return err
+}
+
+func (vm *VM) call(addr uint64, size uint64) error {
+	cs, err := sub(vm.cs, 8)
+	if err != nil {
+		return err
+	}
+	ret, err := add(vm.pc, size)
+	if err != nil {
+		return err
+	}
+	vm.cs = cs
+	buf := make([]uint8, 8)
+	binary.LittleEndian.PutUint64(buf, ret)
+	if err := vm.write(vm.cs, buf); err != nil {
+		return err
+	}
+	vm.pc = addr
+	return nil
+}
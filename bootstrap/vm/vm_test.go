@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"bootstrap/compiler"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func source(t *testing.T, file string) string {
+	t.Helper()
+	dat, err := os.ReadFile("../../" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(dat)
+}
+
+func replace(t *testing.T, src string, old string, new string) string {
+	t.Helper()
+	if !strings.Contains(src, old) {
+		t.Fatalf("missing '%s'", old)
+	}
+	return strings.Replace(src, old, new, 1)
+}
+
+func run(t *testing.T, file string, src string) (*VM, string) {
+	t.Helper()
+	fsys := fstest.MapFS{file: {Data: []uint8(src)}}
+	res, diags := compiler.Compile(context.Background(), compiler.Options{Entry: file, FS: fsys, Builtin: os.DirFS("..")})
+	for _, d := range diags {
+		t.Error(d)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	out := &bytes.Buffer{}
+	m := New(res.Bytes, nil)
+	m.Stdin = strings.NewReader("")
+	m.Stdout = out
+	if err := m.Run(); err != nil {
+		t.Fatalf("run: %s\noutput:\n%s", err, out)
+	}
+	return m, out.String()
+}
+
+func TestFib(t *testing.T) {
+	src := replace(t, source(t, "fib.mvm"), "40u64", "20u64")
+	m, out := run(t, "fib.mvm", src)
+	if want := "args: ''\n\nDebug64: 0x1a6d\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+	if stack := m.Stack(); len(stack) != 0 {
+		t.Errorf("stack % x, want it empty", stack)
+	}
+	if status := m.Status(); status != 0 {
+		t.Errorf("status %d, want 0", status)
+	}
+}
+
+func TestFibStack(t *testing.T) {
+	src := replace(t, source(t, "fib.mvm"), "fun main(:)", "fun{unsafe} main(:)")
+	src = replace(t, src, "40u64 fib(u64:u64) debug(u64:)", "20u64 fib(u64:u64) .asm.halt(:!)")
+	m, out := run(t, "fib.mvm", src)
+	if want := "args: ''\n\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+	stack := m.Stack()
+	if len(stack) != 8 || binary.LittleEndian.Uint64(stack) != 0x1a6d {
+		t.Errorf("stack % x, want fib(20) = 0x1a6d", stack)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	m, out := run(t, "test.mvm", source(t, "test.mvm"))
+	want := "args: ''\n\nDebug64: 0x7\nHey123\n" + strings.Repeat("YEP\n", 5) + ":)\nHey123\n"
+	if out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+	if stack := m.Stack(); len(stack) != 0 {
+		t.Errorf("stack % x, want it empty", stack)
+	}
+}
+
+func TestPanicStatus(t *testing.T) {
+	src := replace(t, source(t, "fib.mvm"), "40u64 fib(u64:u64) debug(u64:)", "\"boom\" panic(string:!)")
+	m, out := run(t, "fib.mvm", src)
+	if !strings.Contains(out, "PANIC: boom") {
+		t.Errorf("output %q, want a panic", out)
+	}
+	if status := m.Status(); status == 0 {
+		t.Errorf("status 0, want non-zero after a panic")
+	}
+}
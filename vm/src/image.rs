@@ -0,0 +1,101 @@
+const MAGIC: &[u8] = b"MyVM";
+const VERSION: u16 = 1;
+const ISA_VERSION: u16 = 2;
+const HEADER_SIZE: usize = 24;
+const SECTION_SIZE: usize = 32;
+const MAX_MEMORY: usize = 1 << 32;
+
+const CODE: u32 = 1;
+const DATA: u32 = 2;
+const STRINGS: u32 = 3;
+const BACKTRACE: u32 = 6;
+
+fn u16_at(bytes: &[u8], at: usize) -> u16 {
+    u16::from_le_bytes(bytes[at..at + 2].try_into().unwrap())
+}
+
+fn u32_at(bytes: &[u8], at: usize) -> u32 {
+    u32::from_le_bytes(bytes[at..at + 4].try_into().unwrap())
+}
+
+fn u64_at(bytes: &[u8], at: usize) -> u64 {
+    u64::from_le_bytes(bytes[at..at + 8].try_into().unwrap())
+}
+
+pub fn is_image(bytes: &[u8]) -> bool {
+    bytes.starts_with(MAGIC)
+}
+
+pub fn load(bytes: &[u8]) -> Result<(Vec<u8>, u64), String> {
+    if bytes.len() < HEADER_SIZE {
+        return Err("truncated header".into());
+    }
+    if u16_at(bytes, 4) != VERSION {
+        return Err(format!("unsupported format version {}", u16_at(bytes, 4)));
+    }
+    if u16_at(bytes, 6) != ISA_VERSION {
+        return Err(format!("unsupported isa version {}", u16_at(bytes, 6)));
+    }
+    let entry = u64_at(bytes, 8);
+    let count = u32_at(bytes, 16) as usize;
+    if HEADER_SIZE + SECTION_SIZE * count > bytes.len() {
+        return Err("truncated section table".into());
+    }
+
+    let mut mem = vec![];
+    let mut used: Vec<(usize, usize)> = vec![];
+    for i in 0..count {
+        let head = HEADER_SIZE + SECTION_SIZE * i;
+        let kind = u32_at(bytes, head);
+        if kind != CODE && kind != DATA && kind != STRINGS && kind != BACKTRACE {
+            continue;
+        }
+        let addr = u64_at(bytes, head + 8) as usize;
+        let offset = u64_at(bytes, head + 16) as usize;
+        let size = u64_at(bytes, head + 24) as usize;
+        let data = offset
+            .checked_add(size)
+            .and_then(|end| bytes.get(offset..end))
+            .ok_or(format!("section {} is out of bounds", kind))?;
+        let end = addr
+            .checked_add(size)
+            .filter(|&end| end <= MAX_MEMORY)
+            .ok_or(format!("section {} doesn't fit into memory", kind))?;
+        if size != 0 && used.iter().any(|&(start, stop)| addr < stop && start < end) {
+            return Err(format!("section {} overlaps another section", kind));
+        }
+        used.push((addr, end));
+        if end > mem.len() {
+            mem.resize(end, 0);
+        }
+        mem[addr..end].copy_from_slice(data);
+    }
+    if entry as usize >= mem.len() {
+        return Err(format!("entry point {:#x} is outside of the loaded sections", entry));
+    }
+    Ok((mem, entry))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn loads_valid_image() {
+        let (mem, entry) = load(include_bytes!("../testdata/valid.img")).unwrap();
+        assert_eq!(mem, [0, 0, 1, 7, b'h', b'i']);
+        assert_eq!(entry, 2);
+    }
+
+    #[test]
+    fn rejects_overlapping_sections() {
+        let err = load(include_bytes!("../testdata/overlap.img")).unwrap_err();
+        assert_eq!(err, "section 2 overlaps another section");
+    }
+
+    #[test]
+    fn rejects_entry_outside_of_sections() {
+        let err = load(include_bytes!("../testdata/entry.img")).unwrap_err();
+        assert_eq!(err, "entry point 0x3 is outside of the loaded sections");
+    }
+}
@@ -1,3 +1,4 @@
+mod image;
 mod mem;
 mod vm;
 
@@ -6,6 +7,7 @@ use std::{
     fs::read,
     io::{stdin, Read},
     path::PathBuf,
+    process::exit,
 };
 
 #[derive(Debug, Options)]
@@ -30,7 +32,18 @@ fn main() {
         stdin().lock().read_to_end(&mut buf).map(|_| buf)
     };
 
-    if let Ok(mut bytes) = bytes_res {
+    if let Ok(bytes) = bytes_res {
+        let (mut bytes, entry) = if image::is_image(&bytes) {
+            match image::load(&bytes) {
+                Ok(res) => res,
+                Err(err) => {
+                    eprintln!("Error: Invalid image: {}", err);
+                    return;
+                }
+            }
+        } else {
+            (bytes, 0)
+        };
         let ptr = bytes.len() as u64;
         if let Some(vargs) = args.vargs {
             let vbytes = vargs.as_bytes();
@@ -42,10 +55,12 @@ fn main() {
         bytes.extend(ptr.to_le_bytes());
 
         #[cfg(feature = "debug")]
-        let vm = vm::VM::<true>::new(bytes);
+        let vm = vm::VM::<true>::new(bytes, entry);
         #[cfg(not(feature = "debug"))]
-        let vm = vm::VM::<false>::new(bytes);
-        vm.run()
+        let vm = vm::VM::<false>::new(bytes, entry);
+        if vm.run() != 0 {
+            exit(1);
+        }
     } else {
         eprintln!("Error: Unable to read input");
     }
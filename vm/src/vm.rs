@@ -80,9 +80,9 @@ pub struct VM<const DEBUG: bool> {
 }
 
 impl<const DEBUG: bool> VM<DEBUG> {
-    pub fn new(code: Vec<u8>) -> Self {
+    pub fn new(code: Vec<u8>, entry: u64) -> Self {
         Self {
-            pc: Reg(0),
+            pc: Reg(entry),
             sp: Reg(code.len() as u64 - 16),
             cs: Reg(0),
             ih: Reg(0),
@@ -91,7 +91,7 @@ impl<const DEBUG: bool> VM<DEBUG> {
         }
     }
 
-    pub fn run(mut self) {
+    pub fn run(mut self) -> i8 {
         loop {
             if let Err(inter) = self._run() {
                 self.ir = inter as i8;
@@ -104,7 +104,7 @@ impl<const DEBUG: bool> VM<DEBUG> {
                         .expect("continue");
                 }
             } else {
-                break;
+                break self.ir;
             }
         }
     }
@@ -711,7 +711,11 @@ impl<const DEBUG: bool> VM<DEBUG> {
                         self.pc.inc_by(1)?;
                     }
                 }
-                098 | 099 => break Err(Inter::InvalidInst),
+                098 => {
+                    self.push(self.cs.0.to_le_bytes())?;
+                    self.pc.inc_by(1)?;
+                }
+                099 => break Err(Inter::InvalidInst),
 
                 100 => {
                     let v2 = u8::from_le_bytes(self.pop()?);